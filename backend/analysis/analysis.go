@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a normalized term with its byte span in the analyzed text
+type Token struct {
+	Term  string `json:"term"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Analyzer turns text into terms: tokenize, lowercase, drop stop words, stem
+type Analyzer struct {
+	Name      string
+	stopWords map[string]bool
+	irregular map[string]string // irregular forms mapped to the base form that is stemmed instead
+	stem      func(string) string
+}
+
+var analyzers = map[string]*Analyzer{
+	"standard": {Name: "standard"},
+	"english":  {Name: "english", stopWords: englishStopWords, irregular: englishIrregulars, stem: StemEnglish},
+	"german":   {Name: "german", stopWords: germanStopWords, stem: StemGerman},
+}
+
+// Get returns the analyzer with the given name
+func Get(name string) (*Analyzer, bool) {
+	a, ok := analyzers[strings.ToLower(name)]
+	return a, ok
+}
+
+// Names returns the names of all available analyzers
+func Names() []string {
+	return []string{"standard", "english", "german"}
+}
+
+// Analyze tokenizes text and returns its terms with their source spans
+func (a *Analyzer) Analyze(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = a.appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = a.appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+// Terms returns only the terms of the analyzed text
+func (a *Analyzer) Terms(text string) []string {
+	tokens := a.Analyze(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+// appendToken normalizes text[start:end] and appends it unless it is a stop word
+func (a *Analyzer) appendToken(tokens []Token, text string, start, end int) []Token {
+	term := strings.ToLower(text[start:end])
+	if a.stopWords[term] {
+		return tokens
+	}
+	if base, ok := a.irregular[term]; ok {
+		term = base
+	}
+	if a.stem != nil {
+		term = a.stem(term)
+	}
+	if term == "" {
+		return tokens
+	}
+	return append(tokens, Token{Term: term, Start: start, End: end})
+}
+
+// isASCII reports whether s only contains ASCII characters
+func isASCII(s string) bool {
+	return utf8.RuneCountInString(s) == len(s)
+}
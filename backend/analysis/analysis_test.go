@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"testing"
+)
+
+func TestStemEnglish(t *testing.T) {
+	tests := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"ties":           "tie",
+		"cats":           "cat",
+		"agreed":         "agre",
+		"plastered":      "plaster",
+		"motoring":       "motor",
+		"sing":           "sing",
+		"hopping":        "hop",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"generalization": "general",
+		"generously":     "generous",
+		"running":        "run",
+		"runs":           "run",
+		"connection":     "connect",
+		"skies":          "sky",
+	}
+	for word, want := range tests {
+		if got := StemEnglish(word); got != want {
+			t.Errorf("StemEnglish(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestStemGerman(t *testing.T) {
+	tests := map[string]string{
+		"häuser":      "haus",
+		"katzen":      "katz",
+		"laufen":      "lauf",
+		"straße":      "strass",
+		"zeitung":     "zeitung",
+		"bedeutung":   "bedeut",
+		"freiheit":    "freiheit",
+		"möglichkeit": "moglich",
+	}
+	for word, want := range tests {
+		if got := StemGerman(word); got != want {
+			t.Errorf("StemGerman(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	a, _ := Get("english")
+	tokens := a.Analyze("The dogs were running home.")
+
+	want := []Token{
+		{Term: "dog", Start: 4, End: 8},
+		{Term: "run", Start: 14, End: 21},
+		{Term: "home", Start: 22, End: 26},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("Expected %d tokens, got %+v", len(want), tokens)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("Token %d: expected %+v, got %+v", i, want[i], tokens[i])
+		}
+	}
+}
+
+func TestAnalyzeIrregular(t *testing.T) {
+	a, _ := Get("english")
+	tests := map[string]string{
+		"ran":      "running",
+		"runs":     "running",
+		"began":    "beginning",
+		"children": "child",
+		"bought":   "buying",
+	}
+	for form, other := range tests {
+		if got, want := a.Terms(form), a.Terms(other); len(got) != 1 || got[0] != want[0] {
+			t.Errorf("Terms(%q) = %q, want %q", form, got, want)
+		}
+	}
+}
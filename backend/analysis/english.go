@@ -0,0 +1,265 @@
+package analysis
+
+import (
+	"strings"
+)
+
+// englishExceptions are stemmed to fixed forms before any step runs
+var englishExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie", "tying": "tie",
+	"idly": "idl", "gently": "gentl", "ugly": "ugli", "early": "earli", "only": "onli",
+	"singly": "singl", "sky": "sky", "news": "news", "howe": "howe",
+	"atlas": "atlas", "cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+// englishInvariants are left alone once step 1a has run
+var englishInvariants = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true,
+	"earring": true, "proceed": true, "exceed": true, "succeed": true,
+}
+
+// StemEnglish implements the Snowball (Porter2) English stemmer.
+// Words with non-ASCII letters are returned unchanged.
+func StemEnglish(word string) string {
+	if len(word) <= 2 || !isASCII(word) {
+		return word
+	}
+	if s, ok := englishExceptions[word]; ok {
+		return s
+	}
+
+	w := []byte(strings.TrimPrefix(word, "'"))
+	for i := range w {
+		if w[i] == 'y' && (i == 0 || isEnVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+	r1, r2 := englishRegions(w)
+
+	w = enStep0(w)
+	w = enStep1a(w)
+	if englishInvariants[string(w)] {
+		return string(w)
+	}
+	w = enStep1b(w, r1)
+	w = enStep1c(w)
+	w = enStep2(w, r1)
+	w = enStep3(w, r1, r2)
+	w = enStep4(w, r2)
+	w = enStep5(w, r1, r2)
+
+	return strings.ReplaceAll(string(w), "Y", "y")
+}
+
+func isEnVowel(c byte) bool {
+	switch c {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+// englishRegions computes R1 and R2 as start offsets into w
+func englishRegions(w []byte) (int, int) {
+	r1 := len(w)
+	s := string(w)
+	switch {
+	case strings.HasPrefix(s, "gener"), strings.HasPrefix(s, "arsen"):
+		r1 = 5
+	case strings.HasPrefix(s, "commun"):
+		r1 = 6
+	default:
+		r1 = regionAfter(w, 0)
+	}
+	return r1, regionAfter(w, r1)
+}
+
+// regionAfter returns the offset after the first non-vowel that follows a vowel, from start
+func regionAfter(w []byte, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isEnVowel(w[i]) && isEnVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// endsShortSyllable reports whether w ends in a short syllable
+func endsShortSyllable(w []byte) bool {
+	n := len(w)
+	if n == 2 {
+		return isEnVowel(w[0]) && !isEnVowel(w[1])
+	}
+	if n < 3 {
+		return false
+	}
+	c := w[n-1]
+	return !isEnVowel(w[n-3]) && isEnVowel(w[n-2]) && !isEnVowel(c) && c != 'w' && c != 'x' && c != 'Y'
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return strings.HasSuffix(string(w), suffix)
+}
+
+// longestSuffix returns the longest of the given suffixes that w ends with
+func longestSuffix(w []byte, suffixes ...string) string {
+	best := ""
+	for _, s := range suffixes {
+		if len(s) > len(best) && hasSuffix(w, s) {
+			best = s
+		}
+	}
+	return best
+}
+
+func containsVowel(w []byte) bool {
+	for _, c := range w {
+		if isEnVowel(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func enStep0(w []byte) []byte {
+	if s := longestSuffix(w, "'s'", "'s", "'"); s != "" {
+		return w[:len(w)-len(s)]
+	}
+	return w
+}
+
+func enStep1a(w []byte) []byte {
+	switch longestSuffix(w, "sses", "ied", "ies", "us", "ss", "s") {
+	case "sses":
+		return w[:len(w)-2]
+	case "ied", "ies":
+		if len(w) > 4 {
+			return append(w[:len(w)-3], 'i')
+		}
+		return append(w[:len(w)-3], 'i', 'e')
+	case "s":
+		// Delete if a vowel occurs before the letter preceding the s
+		if len(w) >= 3 && containsVowel(w[:len(w)-2]) {
+			return w[:len(w)-1]
+		}
+	}
+	return w
+}
+
+func enStep1b(w []byte, r1 int) []byte {
+	switch s := longestSuffix(w, "eed", "eedly", "ed", "edly", "ing", "ingly"); s {
+	case "eed", "eedly":
+		if len(w)-len(s) >= r1 {
+			return append(w[:len(w)-len(s)], 'e', 'e')
+		}
+	case "ed", "edly", "ing", "ingly":
+		stem := w[:len(w)-len(s)]
+		if !containsVowel(stem) {
+			return w
+		}
+		switch {
+		case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+			return append(stem, 'e')
+		case endsDouble(stem):
+			return stem[:len(stem)-1]
+		case endsShortSyllable(stem) && r1 >= len(stem):
+			return append(stem, 'e')
+		}
+		return stem
+	}
+	return w
+}
+
+func endsDouble(w []byte) bool {
+	return longestSuffix(w, "bb", "dd", "ff", "gg", "mm", "nn", "pp", "rr", "tt") != ""
+}
+
+func enStep1c(w []byte) []byte {
+	n := len(w)
+	if n > 2 && (w[n-1] == 'y' || w[n-1] == 'Y') && !isEnVowel(w[n-2]) {
+		w[n-1] = 'i'
+	}
+	return w
+}
+
+var enStep2Rules = map[string]string{
+	"tional": "tion", "enci": "ence", "anci": "ance", "abli": "able", "entli": "ent",
+	"izer": "ize", "ization": "ize", "ational": "ate", "ation": "ate", "ator": "ate",
+	"alism": "al", "aliti": "al", "alli": "al", "fulness": "ful", "ousli": "ous",
+	"ousness": "ous", "iveness": "ive", "iviti": "ive", "biliti": "ble", "bli": "ble",
+	"ogi": "og", "fulli": "ful", "lessli": "less", "li": "",
+}
+
+var enStep2Suffixes = keys(enStep2Rules)
+
+func enStep2(w []byte, r1 int) []byte {
+	s := longestSuffix(w, enStep2Suffixes...)
+	if s == "" || len(w)-len(s) < r1 {
+		return w
+	}
+	stem := w[:len(w)-len(s)]
+	switch s {
+	case "ogi":
+		if !hasSuffix(stem, "l") {
+			return w
+		}
+	case "li":
+		if len(stem) == 0 || !strings.ContainsRune("cdeghkmnrt", rune(stem[len(stem)-1])) {
+			return w
+		}
+	}
+	return append(stem, enStep2Rules[s]...)
+}
+
+var enStep3Rules = map[string]string{
+	"tional": "tion", "ational": "ate", "alize": "al", "icate": "ic",
+	"iciti": "ic", "ical": "ic", "ful": "", "ness": "", "ative": "",
+}
+
+var enStep3Suffixes = keys(enStep3Rules)
+
+func enStep3(w []byte, r1, r2 int) []byte {
+	s := longestSuffix(w, enStep3Suffixes...)
+	if s == "" || len(w)-len(s) < r1 {
+		return w
+	}
+	if s == "ative" && len(w)-len(s) < r2 {
+		return w
+	}
+	return append(w[:len(w)-len(s)], enStep3Rules[s]...)
+}
+
+func enStep4(w []byte, r2 int) []byte {
+	s := longestSuffix(w, "al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement",
+		"ment", "ent", "ism", "ate", "iti", "ous", "ive", "ize", "ion")
+	if s == "" || len(w)-len(s) < r2 {
+		return w
+	}
+	stem := w[:len(w)-len(s)]
+	if s == "ion" && !hasSuffix(stem, "s") && !hasSuffix(stem, "t") {
+		return w
+	}
+	return stem
+}
+
+func enStep5(w []byte, r1, r2 int) []byte {
+	n := len(w)
+	switch {
+	case n > 0 && w[n-1] == 'e':
+		stem := w[:n-1]
+		if n-1 >= r2 || (n-1 >= r1 && !endsShortSyllable(stem)) {
+			return stem
+		}
+	case n > 1 && w[n-1] == 'l' && w[n-2] == 'l' && n-1 >= r2:
+		return w[:n-1]
+	}
+	return w
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
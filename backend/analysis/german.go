@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"strings"
+)
+
+// StemGerman implements the Snowball German stemmer
+func StemGerman(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ß", "ss"))
+
+	// Mark u and y between vowels as consonants
+	for i := 1; i < len(w)-1; i++ {
+		if (w[i] == 'u' || w[i] == 'y') && isDeVowel(w[i-1]) && isDeVowel(w[i+1]) {
+			w[i] = w[i] - 'a' + 'A'
+		}
+	}
+
+	r1 := deRegionAfter(w, 0)
+	r2 := deRegionAfter(w, r1)
+	// The region before R1 must contain at least three letters
+	r1 = max(r1, 3)
+
+	w = deStep1(w, r1)
+	w = deStep2(w, r1)
+	w = deStep3(w, r1, r2)
+
+	return strings.NewReplacer("U", "u", "Y", "y", "ä", "a", "ö", "o", "ü", "u").Replace(string(w))
+}
+
+func isDeVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u', 'y', 'ä', 'ö', 'ü':
+		return true
+	}
+	return false
+}
+
+func deRegionAfter(w []rune, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isDeVowel(w[i]) && isDeVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+func runeSuffix(w []rune, suffixes ...string) string {
+	s := string(w)
+	best := ""
+	for _, suf := range suffixes {
+		if len(suf) > len(best) && strings.HasSuffix(s, suf) {
+			best = suf
+		}
+	}
+	return best
+}
+
+func endsIn(w []rune, set string) bool {
+	return len(w) > 0 && strings.ContainsRune(set, w[len(w)-1])
+}
+
+func deStep1(w []rune, r1 int) []rune {
+	s := runeSuffix(w, "em", "ern", "er", "e", "en", "es", "s")
+	if s == "" || len(w)-len(s) < r1 {
+		return w
+	}
+	stem := w[:len(w)-len(s)]
+	switch s {
+	case "e", "en", "es":
+		if runeSuffix(stem, "niss") != "" {
+			stem = stem[:len(stem)-1]
+		}
+	case "s":
+		if !endsIn(stem, "bdfghklmnrt") {
+			return w
+		}
+	}
+	return stem
+}
+
+func deStep2(w []rune, r1 int) []rune {
+	s := runeSuffix(w, "en", "er", "est", "st")
+	if s == "" || len(w)-len(s) < r1 {
+		return w
+	}
+	stem := w[:len(w)-len(s)]
+	if s == "st" && (!endsIn(stem, "bdfghklmnt") || len(stem) < 4) {
+		return w
+	}
+	return stem
+}
+
+func deStep3(w []rune, r1, r2 int) []rune {
+	s := runeSuffix(w, "end", "ung", "ig", "ik", "isch", "lich", "heit", "keit")
+	if s == "" || len(w)-len(s) < r2 {
+		return w
+	}
+	stem := w[:len(w)-len(s)]
+	switch s {
+	case "end", "ung":
+		if runeSuffix(stem, "ig") != "" && len(stem)-2 >= r2 && !endsIn(stem[:len(stem)-2], "e") {
+			stem = stem[:len(stem)-2]
+		}
+	case "ig", "ik", "isch":
+		if endsIn(stem, "e") {
+			return w
+		}
+	case "lich", "heit":
+		if p := runeSuffix(stem, "er", "en"); p != "" && len(stem)-2 >= r1 {
+			stem = stem[:len(stem)-2]
+		}
+	case "keit":
+		if p := runeSuffix(stem, "lich", "ig"); p != "" && len(stem)-len([]rune(p)) >= r2 {
+			stem = stem[:len(stem)-len([]rune(p))]
+		}
+	}
+	return stem
+}
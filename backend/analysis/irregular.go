@@ -0,0 +1,31 @@
+package analysis
+
+// englishIrregulars maps irregular inflections of common English verbs and
+// nouns, which no suffix rule can reduce, to their base forms. Forms that
+// are stop words, such as "was" or "had", or that mostly mean something
+// else, such as "left" or "saw", are not listed.
+var englishIrregulars = map[string]string{
+	"ran": "run", "began": "begin", "begun": "begin", "came": "come",
+	"went": "go", "gone": "go", "seen": "see", "took": "take", "taken": "take",
+	"gave": "give", "given": "give", "wrote": "write", "written": "write",
+	"spoke": "speak", "spoken": "speak", "drove": "drive", "driven": "drive",
+	"ate": "eat", "eaten": "eat", "drank": "drink", "drunk": "drink",
+	"sang": "sing", "sung": "sing", "swam": "swim", "swum": "swim",
+	"knew": "know", "known": "know", "grew": "grow", "grown": "grow",
+	"threw": "throw", "thrown": "throw", "flew": "fly", "flown": "fly",
+	"drew": "draw", "drawn": "draw", "chose": "choose", "chosen": "choose",
+	"froze": "freeze", "frozen": "freeze", "stole": "steal", "stolen": "steal",
+	"broke": "break", "broken": "break", "forgot": "forget", "forgotten": "forget",
+	"got": "get", "gotten": "get", "made": "make", "paid": "pay", "said": "say",
+	"sold": "sell", "told": "tell", "thought": "think", "brought": "bring",
+	"bought": "buy", "caught": "catch", "taught": "teach", "sought": "seek",
+	"fought": "fight", "kept": "keep", "slept": "sleep", "felt": "feel",
+	"meant": "mean", "sent": "send", "spent": "spend", "built": "build",
+	"lost": "lose", "held": "hold", "stood": "stand", "understood": "understand",
+	"sat": "sit", "won": "win", "met": "meet", "fled": "flee", "heard": "hear",
+	"wore": "wear", "worn": "wear", "tore": "tear", "torn": "tear",
+	"rode": "ride", "ridden": "ride", "rose": "rise", "risen": "rise",
+	"hid": "hide", "hidden": "hide", "fell": "fall", "fallen": "fall",
+	"found": "find", "children": "child", "men": "man", "women": "woman", "feet": "foot",
+	"teeth": "tooth", "mice": "mouse",
+}
@@ -0,0 +1,35 @@
+package analysis
+
+import (
+	"strings"
+)
+
+var englishStopWords = wordSet(`
+a about above after again against all am an and any are as at be because been
+before being below between both but by can could did do does doing down during
+each few for from further had has have having he her here hers herself him
+himself his how i if in into is it its itself just me more most my myself no nor
+not now of off on once only or other our ours ourselves out over own same she
+should so some such than that the their theirs them themselves then there these
+they this those through to too under until up very was we were what when where
+which while who whom why will with would you your yours yourself yourselves`)
+
+var germanStopWords = wordSet(`
+aber alle allem allen aller alles als also am an ander andere anderem anderen
+anderer anderes auch auf aus bei bin bis bist da damit dann das dass dein deine
+dem den der des dich die dies diese diesem diesen dieser dieses dir doch dort du
+durch ein eine einem einen einer eines er es etwas euch euer für gegen gewesen
+hab habe haben hat hatte hier hin hinter ich ihm ihn ihnen ihr ihre im in indem
+ins ist jede jedem jeden jeder jedes jetzt kann kein keine können man manche mein
+meine mich mir mit muss nach nicht nichts noch nun nur ob oder ohne sehr sein
+seine sich sie sind so solche soll sondern über um und uns unser unter viel vom
+von vor war waren was weil welche wenn werde werden wie wieder will wir wird wo
+wollen würde zu zum zur zwar zwischen`)
+
+func wordSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/swanckel93/fuzzy_api/extract"
+)
+
+// Archive formats recognized by Format
+const (
+	FormatZip   = "zip"
+	FormatTar   = "tar"
+	FormatTarGz = "tar.gz"
+)
+
+// ErrLimit is wrapped by errors for archives exceeding Limits
+var ErrLimit = errors.New("archive exceeds limits")
+
+// Limits protect against archives that expand to far more than was uploaded
+type Limits struct {
+	MaxEntries   int   // regular files in the archive
+	MaxEntrySize int64 // uncompressed bytes of a single file
+	MaxTotalSize int64 // uncompressed bytes of all files together
+}
+
+// DefaultLimits are used for archive uploads
+var DefaultLimits = Limits{
+	MaxEntries:   1000,
+	MaxEntrySize: 32 << 20,
+	MaxTotalSize: 256 << 20,
+}
+
+// Format detects whether data is a zip, tar or gzip-compressed tar archive.
+// Zip based documents such as DOCX or EPUB are not archives.
+func Format(filename string, data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		if extract.Sniff(filename, data) == extract.FormatText {
+			return FormatZip
+		}
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		if isTarGz(data) {
+			return FormatTarGz
+		}
+	case isTar(data):
+		return FormatTar
+	}
+	return ""
+}
+
+// isTar checks for the ustar magic of POSIX and GNU tar headers
+func isTar(data []byte) bool {
+	return len(data) >= 262 && bytes.Equal(data[257:262], []byte("ustar"))
+}
+
+// isTarGz checks whether gzip-compressed data, which may be cut off,
+// starts with a tar header, so that compressed single files are not taken
+// for archives
+func isTarGz(data []byte) bool {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(gz, header)
+	return isTar(header[:n])
+}
+
+// IsGzip reports whether data is a single gzip-compressed file, as opposed
+// to a tar.gz archive
+func IsGzip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1f, 0x8b}) && !isTarGz(data)
+}
+
+// Gunzip decompresses a single gzip-compressed file and returns its name
+// without the .gz extension. Reading more than max bytes fails with
+// ErrLimit.
+func Gunzip(name string, r io.Reader, max int64) (string, io.ReadCloser, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".GZ")
+	lr := &limitedReader{r: gz, max: max, err: fmt.Errorf("%w: %s is larger than %d bytes", ErrLimit, name, max)}
+	return name, struct {
+		io.Reader
+		io.Closer
+	}{lr, gz}, nil
+}
+
+// limitedReader fails with err once more than max bytes were read
+type limitedReader struct {
+	r         io.Reader
+	max, read int64
+	err       error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, l.err
+	}
+	return n, err
+}
+
+// sizedReaderAt is random access to content of a known size, such as a
+// bytes.Reader
+type sizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// Walk calls fn with the name and content of every regular file of an
+// archive in the given format, in the order they are stored, so that no
+// more than one file is read at a time. Directories, links and metadata
+// such as __MACOSX are left out. Reading past the limits fails with
+// ErrLimit. Zip archives need random access and are read into memory
+// unless r provides it. An error of fn ends the walk and is returned.
+func Walk(r io.Reader, format string, limits Limits, fn func(name string, r io.Reader) error) error {
+	w := &walker{limits: limits, fn: fn}
+	switch format {
+	case FormatZip:
+		ra, ok := r.(sizedReaderAt)
+		if !ok {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			ra = bytes.NewReader(data)
+		}
+		return w.zip(ra)
+	case FormatTar:
+		return w.tar(r)
+	case FormatTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return w.tar(gz)
+	}
+	return fmt.Errorf("unknown archive format %q", format)
+}
+
+// walker passes the files of an archive to fn while enforcing the limits
+type walker struct {
+	limits  Limits
+	fn      func(name string, r io.Reader) error
+	entries int
+	total   int64
+}
+
+func (w *walker) zip(r sizedReaderAt) error {
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || skipped(f.Name) {
+			continue
+		}
+		// Declared sizes can lie, so they only reject early and the
+		// actual content is limited while reading
+		if f.UncompressedSize64 > uint64(w.limits.MaxEntrySize) {
+			return fmt.Errorf("%w: %s is larger than %d bytes", ErrLimit, f.Name, w.limits.MaxEntrySize)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = w.visit(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg || skipped(h.Name) {
+			continue
+		}
+		if h.Size > w.limits.MaxEntrySize {
+			return fmt.Errorf("%w: %s is larger than %d bytes", ErrLimit, h.Name, w.limits.MaxEntrySize)
+		}
+		if err := w.visit(h.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// visit passes a file to fn, limited to what is left of the limits
+func (w *walker) visit(name string, r io.Reader) error {
+	if w.entries >= w.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d files", ErrLimit, w.limits.MaxEntries)
+	}
+	w.entries++
+
+	lr := &limitedReader{r: r, max: w.limits.MaxEntrySize, err: fmt.Errorf("%w: %s is larger than %d bytes", ErrLimit, name, w.limits.MaxEntrySize)}
+	if left := w.limits.MaxTotalSize - w.total; left < lr.max {
+		lr.max, lr.err = left, fmt.Errorf("%w: more than %d bytes in total", ErrLimit, w.limits.MaxTotalSize)
+	}
+	err := w.fn(strings.TrimPrefix(path.Clean("/"+name), "/"), lr)
+	w.total += lr.read
+	return err
+}
+
+// skipped reports whether an entry is operating system metadata rather
+// than a document
+func skipped(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, "._") ||
+		base == ".DS_Store" || base == "Thumbs.db"
+}
@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func buildZip(files map[string]string, names ...string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, _ := zw.Create(name)
+		w.Write([]byte(files[name]))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func buildTar(gz bool, files map[string]string, names ...string) []byte {
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var gw *gzip.Writer
+	if gz {
+		gw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gw)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	tw.WriteHeader(&tar.Header{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, name := range names {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[name]))})
+		tw.Write([]byte(files[name]))
+	}
+	tw.WriteHeader(&tar.Header{Name: "docs/link.txt", Typeflag: tar.TypeSymlink, Linkname: "a.txt"})
+	tw.Close()
+	if gw != nil {
+		gw.Close()
+	}
+	return buf.Bytes()
+}
+
+// entry is a file passed to the function of Walk
+type entry struct {
+	name, data string
+}
+
+// walk returns the files of an archive as Walk passes them
+func walk(r io.Reader, format string, limits Limits) ([]entry, error) {
+	var out []entry
+	err := Walk(r, format, limits, func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		out = append(out, entry{name, string(data)})
+		return err
+	})
+	return out, err
+}
+
+func TestWalk(t *testing.T) {
+	files := map[string]string{
+		"docs/a.txt":        "Hello there.",
+		"./docs/b.md":       "# Title",
+		"__MACOSX/docs/._a": "junk",
+		"docs/.DS_Store":    "junk",
+		"../escape/c.txt":   "Outside.",
+	}
+	names := []string{"docs/a.txt", "./docs/b.md", "__MACOSX/docs/._a", "docs/.DS_Store", "../escape/c.txt"}
+	want := []entry{
+		{"docs/a.txt", "Hello there."},
+		{"docs/b.md", "# Title"},
+		{"escape/c.txt", "Outside."},
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{"zip", buildZip(files, names...), FormatZip},
+		{"tar", buildTar(false, files, names...), FormatTar},
+		{"tar.gz", buildTar(true, files, names...), FormatTarGz},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format("upload."+tt.name, tt.data); got != tt.format {
+				t.Fatalf("Expected format %q, got %q", tt.format, got)
+			}
+			// Without random access, as uploads are read
+			entries, err := walk(io.MultiReader(bytes.NewReader(tt.data)), tt.format, DefaultLimits)
+			if err != nil {
+				t.Fatalf("Walk failed: %v", err)
+			}
+			if !reflect.DeepEqual(entries, want) {
+				t.Errorf("Expected %q, got %q", want, entries)
+			}
+		})
+	}
+}
+
+func TestFormatIgnoresDocuments(t *testing.T) {
+	docx := buildZip(map[string]string{"word/document.xml": "<w:document/>"}, "word/document.xml")
+	if got := Format("report.docx", docx); got != "" {
+		t.Errorf("Expected DOCX not to be an archive, got %q", got)
+	}
+	if got := Format("notes.txt", []byte("plain text")); got != "" {
+		t.Errorf("Expected text not to be an archive, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(bytes.Repeat([]byte("plain text\n"), 100))
+	gw.Close()
+	if got := Format("notes.txt.gz", buf.Bytes()); got != "" {
+		t.Errorf("Expected compressed text not to be an archive, got %q", got)
+	}
+	// Only the start of an upload is sniffed
+	tgz := buildTar(true, map[string]string{"docs/a.txt": "alpha"}, "docs/a.txt")
+	if got := Format("docs.tgz", tgz[:len(tgz)/2]); got != FormatTarGz {
+		t.Errorf("Expected the start of a tar.gz to be one, got %q", got)
+	}
+}
+
+func TestWalkLimits(t *testing.T) {
+	files := map[string]string{"a.txt": "aaaa", "b.txt": "bbbb", "c.txt": "cccc"}
+	data := buildZip(files, "a.txt", "b.txt", "c.txt")
+
+	tests := []struct {
+		name   string
+		limits Limits
+	}{
+		{"too many entries", Limits{MaxEntries: 2, MaxEntrySize: 100, MaxTotalSize: 100}},
+		{"entry too large", Limits{MaxEntries: 10, MaxEntrySize: 3, MaxTotalSize: 100}},
+		{"total too large", Limits{MaxEntries: 10, MaxEntrySize: 100, MaxTotalSize: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := walk(bytes.NewReader(data), FormatZip, tt.limits); !errors.Is(err, ErrLimit) {
+				t.Errorf("Test %q failed. Got: %v", tt.name, err)
+			}
+		})
+	}
+
+	// Limits apply to decompressed content, not to the upload size
+	big := buildTar(true, map[string]string{"big.txt": string(bytes.Repeat([]byte("x"), 1<<16))}, "big.txt")
+	if _, err := walk(bytes.NewReader(big), FormatTarGz, Limits{MaxEntries: 10, MaxEntrySize: 1 << 20, MaxTotalSize: 1 << 10}); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected total size limit for tar.gz, got %v", err)
+	}
+}
+
+func TestGunzip(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte("plain text"))
+	gw.Close()
+	if !IsGzip(buf.Bytes()) || IsGzip(buildTar(true, map[string]string{"a.txt": "a"}, "a.txt")) {
+		t.Errorf("Expected only the compressed file to be gzip")
+	}
+
+	name, r, err := Gunzip("notes.txt.gz", bytes.NewReader(buf.Bytes()), 1<<20)
+	if err != nil {
+		t.Fatalf("Gunzip failed: %v", err)
+	}
+	if data, err := io.ReadAll(r); err != nil || name != "notes.txt" || string(data) != "plain text" {
+		t.Errorf("Expected notes.txt with its text, got %q, %q, %v", name, data, err)
+	}
+
+	_, r, _ = Gunzip("notes.txt.gz", bytes.NewReader(buf.Bytes()), 5)
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected ErrLimit, got %v", err)
+	}
+}
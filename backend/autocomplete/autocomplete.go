@@ -0,0 +1,35 @@
+package autocomplete
+
+import (
+	"sync"
+)
+
+// indexStore holds one trie per uploaded document
+type indexStore struct {
+	mu    sync.RWMutex
+	tries map[string]*Trie
+}
+
+var indexes = &indexStore{
+	tries: make(map[string]*Trie),
+}
+
+// AddDocument builds and stores the completion trie for a document,
+// replacing the trie of a previous version
+func AddDocument(docID string, sentences []string) {
+	t := Build(sentences)
+	indexes.mu.Lock()
+	defer indexes.mu.Unlock()
+	indexes.tries[docID] = t
+}
+
+// Complete returns completions for prefix in the given document
+func Complete(docID, prefix string, limit int) ([]Completion, bool) {
+	indexes.mu.RLock()
+	t, ok := indexes.tries[docID]
+	indexes.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return t.Complete(prefix, limit), true
+}
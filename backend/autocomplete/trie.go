@@ -0,0 +1,184 @@
+package autocomplete
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxEdits is the fuzzy tolerance applied to the typed prefix
+	maxEdits = 1
+	// topK is the number of completions precomputed for every trie node
+	topK = 20
+	// minPhraseFreq is how often a two-word phrase must occur to be indexed
+	minPhraseFreq = 2
+)
+
+// MaxLimit is the most completions Complete returns for a prefix
+const MaxLimit = topK
+
+// Completion is a suggested word or phrase for a prefix
+type Completion struct {
+	Text      string `json:"text"`
+	Frequency int    `json:"frequency"`
+	Distance  int    `json:"distance"`
+}
+
+// node is a single trie node keyed by rune
+type node struct {
+	children map[rune]*node
+	term     string // set if a word or phrase ends here
+	freq     int
+	top      []Completion // best completions in this subtree, by frequency
+}
+
+// Trie is a prefix tree of the words and frequent phrases of one document
+type Trie struct {
+	root *node
+}
+
+// Build indexes every word and every two-word phrase that occurs at least
+// minPhraseFreq times in the given sentences
+func Build(sentences []string) *Trie {
+	words := make(map[string]int)
+	phrases := make(map[string]int)
+
+	for _, s := range sentences {
+		tokens := tokenize(s)
+		for i, tok := range tokens {
+			words[tok]++
+			if i > 0 {
+				phrases[tokens[i-1]+" "+tok]++
+			}
+		}
+	}
+
+	t := &Trie{root: &node{}}
+	for w, f := range words {
+		t.insert(w, f)
+	}
+	for p, f := range phrases {
+		if f >= minPhraseFreq {
+			t.insert(p, f)
+		}
+	}
+	t.root.computeTop()
+	return t
+}
+
+// insert adds a term with its frequency
+func (t *Trie) insert(term string, freq int) {
+	n := t.root
+	for _, r := range term {
+		if n.children == nil {
+			n.children = make(map[rune]*node)
+		}
+		child, ok := n.children[r]
+		if !ok {
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+	}
+	n.term = term
+	n.freq = freq
+}
+
+// computeTop fills n.top (and that of all descendants) with the topK most
+// frequent terms below n, so lookups never have to walk whole subtrees
+func (n *node) computeTop() {
+	var candidates []Completion
+	if n.term != "" {
+		candidates = append(candidates, Completion{Text: n.term, Frequency: n.freq})
+	}
+	for _, child := range n.children {
+		child.computeTop()
+		candidates = append(candidates, child.top...)
+	}
+	sortCompletions(candidates)
+	if len(candidates) > topK {
+		candidates = candidates[:topK]
+	}
+	n.top = candidates
+}
+
+// Complete returns up to limit completions for prefix, tolerating one edit
+// in the prefix. Exact prefix matches rank before fuzzy ones, then by frequency.
+func (t *Trie) Complete(prefix string, limit int) []Completion {
+	if limit <= 0 || limit > topK {
+		limit = topK
+	}
+	query := []rune(strings.ToLower(strings.TrimSpace(prefix)))
+
+	// Levenshtein row for the empty path
+	row := make([]int, len(query)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	best := make(map[string]Completion)
+	collect(t.root, query, row, best)
+
+	results := make([]Completion, 0, len(best))
+	for _, c := range best {
+		results = append(results, c)
+	}
+	sortCompletions(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// collect walks the trie computing one Levenshtein row per node and gathers
+// the precomputed completions of every node whose path is within maxEdits of query
+func collect(n *node, query []rune, row []int, best map[string]Completion) {
+	if dist := row[len(query)]; dist <= maxEdits {
+		for _, c := range n.top {
+			if prev, ok := best[c.Text]; !ok || dist < prev.Distance {
+				c.Distance = dist
+				best[c.Text] = c
+			}
+		}
+	}
+
+	for r, child := range n.children {
+		next := make([]int, len(row))
+		next[0] = row[0] + 1
+		minDist := next[0]
+		for i := 1; i < len(row); i++ {
+			cost := 1
+			if query[i-1] == r {
+				cost = 0
+			}
+			next[i] = min(next[i-1]+1, row[i]+1, row[i-1]+cost)
+			minDist = min(minDist, next[i])
+		}
+		// No extension of this path can get back within tolerance
+		if minDist <= maxEdits {
+			collect(child, query, next, best)
+		}
+	}
+}
+
+// sortCompletions orders by distance, then frequency, then alphabetically
+func sortCompletions(c []Completion) {
+	sort.Slice(c, func(i, j int) bool {
+		switch {
+		case c[i].Distance != c[j].Distance:
+			return c[i].Distance < c[j].Distance
+		case c[i].Frequency != c[j].Frequency:
+			return c[i].Frequency > c[j].Frequency
+		default:
+			return c[i].Text < c[j].Text
+		}
+	})
+}
+
+// tokenize lowercases s and splits it into words of letters and digits
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,75 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	trie := Build([]string{
+		"The invoice was sent.",
+		"Invoices are due monthly.",
+		"The invoice was paid.",
+		"An investor called.",
+	})
+
+	tests := []struct {
+		name     string
+		prefix   string
+		expected func([]Completion) bool
+	}{
+		{
+			name:   "Exact prefix ranked by frequency",
+			prefix: "inv",
+			expected: func(c []Completion) bool {
+				return len(c) >= 3 && c[0].Text == "invoice" && c[0].Frequency == 2 && c[0].Distance == 0
+			},
+		},
+		{
+			name:   "One edit tolerated",
+			prefix: "imvo",
+			expected: func(c []Completion) bool {
+				for _, comp := range c {
+					if comp.Text == "invoice" && comp.Distance == 1 {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		{
+			name:   "Frequent phrase indexed",
+			prefix: "the inv",
+			expected: func(c []Completion) bool {
+				return len(c) > 0 && c[0].Text == "the invoice"
+			},
+		},
+		{
+			name:   "Rare phrase not indexed",
+			prefix: "an inv",
+			expected: func(c []Completion) bool {
+				for _, comp := range c {
+					if comp.Text == "an investor" {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:   "Two edits rejected",
+			prefix: "xxvoice",
+			expected: func(c []Completion) bool {
+				return len(c) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trie.Complete(tt.prefix, 10)
+			if !tt.expected(got) {
+				t.Errorf("Test %q failed. Got: %+v", tt.name, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encodings recognized by Detect and Decode
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Windows1252 = "windows-1252"
+	ISO88591    = "iso-8859-1"
+)
+
+// ErrUnknown is returned for encoding names that Decode does not support
+var ErrUnknown = errors.New("unknown encoding")
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// aliases maps common labels of the supported encodings to their names
+var aliases = map[string]string{
+	"utf8": UTF8, "utf-8": UTF8, "unicode-1-1-utf-8": UTF8,
+	"utf-16": UTF16LE, "utf16": UTF16LE, "utf-16le": UTF16LE, "utf16le": UTF16LE, "ucs-2": UTF16LE,
+	"utf-16be": UTF16BE, "utf16be": UTF16BE,
+	"windows-1252": Windows1252, "cp1252": Windows1252, "x-cp1252": Windows1252,
+	"iso-8859-1": ISO88591, "iso8859-1": ISO88591, "latin1": ISO88591, "latin-1": ISO88591,
+	"l1": ISO88591, "iso_8859-1": ISO88591,
+}
+
+// Supported lists the encoding names accepted by Decode
+func Supported() []string {
+	return []string{UTF8, UTF16LE, UTF16BE, Windows1252, ISO88591}
+}
+
+// Normalize returns the canonical name of an encoding label, e.g. "utf-8"
+// for "UTF8", or "" if the encoding is not supported
+func Normalize(label string) string {
+	return aliases[strings.ToLower(strings.TrimSpace(label))]
+}
+
+// Detect guesses the encoding of text, or of a prefix of it, from its byte
+// order mark, the distribution of zero bytes and UTF-8 validity. Bytes that
+// are neither UTF-8 nor UTF-16 are taken as Windows-1252 if they use its
+// printable range 0x80-0x9F, and as ISO-8859-1 otherwise.
+func Detect(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return UTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return UTF16BE
+	}
+	if enc := detectUTF16(data); enc != "" {
+		return enc
+	}
+	if utf8.Valid(trimPartialRune(data)) {
+		return UTF8
+	}
+	for _, c := range data {
+		if c >= 0x80 && c < 0xA0 {
+			return Windows1252
+		}
+	}
+	return ISO88591
+}
+
+// trimPartialRune drops a multi-byte sequence cut off at the end of data,
+// which happens when only a prefix of a file is inspected
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// detectUTF16 recognizes UTF-16 without a byte order mark by the zero high
+// bytes of ASCII characters, which make up most of typical text
+func detectUTF16(data []byte) string {
+	sample := data[:min(len(data), 4096)]
+	if len(sample) < 4 {
+		return ""
+	}
+	var even, odd int
+	for i, c := range sample {
+		if c != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case odd*10 >= pairs*4 && even*20 < pairs:
+		return UTF16LE
+	case even*10 >= pairs*4 && odd*20 < pairs:
+		return UTF16BE
+	}
+	return ""
+}
+
+// Decode converts data in the given encoding to UTF-8, dropping a byte order
+// mark. Invalid sequences become U+FFFD.
+func Decode(data []byte, encoding string) (string, error) {
+	r, err := NewReader(bytes.NewReader(data), encoding)
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(r)
+	return string(out), err
+}
+
+// NewReader returns a reader that converts r from the given encoding to
+// UTF-8 while it is read, dropping a byte order mark. Invalid sequences
+// become U+FFFD.
+func NewReader(r io.Reader, name string) (io.Reader, error) {
+	var enc encoding.Encoding
+	switch Normalize(name) {
+	case UTF8:
+		enc = unicode.UTF8BOM
+	case UTF16LE:
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case UTF16BE:
+		enc = unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case Windows1252:
+		enc = charmap.Windows1252
+	case ISO88591:
+		enc = charmap.ISO8859_1
+	default:
+		return nil, ErrUnknown
+	}
+	return transform.NewReader(r, enc.NewDecoder()), nil
+}
+
+// DecodeWindows1252 converts Windows-1252 bytes to UTF-8
+func DecodeWindows1252(b []byte) string {
+	out, _ := charmap.Windows1252.NewDecoder().Bytes(b)
+	return string(out)
+}
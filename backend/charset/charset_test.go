@@ -0,0 +1,95 @@
+package charset
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, bigEndian, bom bool) []byte {
+	var out []byte
+	if bom {
+		s = "\ufeff" + s
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func TestDetectAndDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		encoding string
+		want     string
+	}{
+		{"UTF-8", []byte("Café crème"), UTF8, "Café crème"},
+		{"UTF-8 with BOM", []byte("\xef\xbb\xbfNaïve"), UTF8, "Naïve"},
+		{"ASCII", []byte("plain text"), UTF8, "plain text"},
+		{"UTF-16LE with BOM", utf16Bytes("Grüße aus Köln", false, true), UTF16LE, "Grüße aus Köln"},
+		{"UTF-16BE with BOM", utf16Bytes("Grüße", true, true), UTF16BE, "Grüße"},
+		{"UTF-16LE without BOM", utf16Bytes("Hello there, world", false, false), UTF16LE, "Hello there, world"},
+		{"UTF-16BE without BOM", utf16Bytes("Hello there, world", true, false), UTF16BE, "Hello there, world"},
+		{"Windows-1252", []byte("\x93Caf\xe9\x94 costs \x805"), Windows1252, "“Café” costs €5"},
+		{"ISO-8859-1", []byte("Fran\xe7ais \xe0 la carte"), ISO88591, "Français à la carte"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.input); got != tt.encoding {
+				t.Fatalf("Test %q failed. Detected: %q", tt.name, got)
+			}
+			got, err := Decode(tt.input, tt.encoding)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Test %q failed. Got: %q", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestDecodeOverride(t *testing.T) {
+	got, err := Decode([]byte("Gr\xfc\xdfe"), "Latin1")
+	if err != nil || got != "Grüße" {
+		t.Errorf("Expected Latin1 alias to decode, got %q, %v", got, err)
+	}
+	got, err = Decode([]byte("bad \xff byte"), "UTF8")
+	if err != nil || got != "bad � byte" {
+		t.Errorf("Expected invalid UTF-8 to be replaced, got %q, %v", got, err)
+	}
+	if _, err := Decode([]byte("x"), "ebcdic"); err != ErrUnknown {
+		t.Errorf("Expected ErrUnknown, got %v", err)
+	}
+}
+
+func TestNewReaderSmallReads(t *testing.T) {
+	input := "Größe 😀 und €uro"
+	tests := []struct {
+		encoding string
+		data     []byte
+	}{
+		{UTF8, []byte("\xef\xbb\xbf" + input)},
+		{UTF16LE, utf16Bytes(input, false, true)},
+		{UTF16BE, utf16Bytes(input, true, false)},
+	}
+	for _, tt := range tests {
+		r, err := NewReader(iotest.OneByteReader(strings.NewReader(string(tt.data))), tt.encoding)
+		if err != nil {
+			t.Fatalf("NewReader failed: %v", err)
+		}
+		// One byte at a time splits every multi-byte rune across reads
+		got, err := io.ReadAll(iotest.OneByteReader(r))
+		if err != nil || string(got) != input {
+			t.Errorf("Test %q failed. Got: %q, %v", tt.encoding, got, err)
+		}
+	}
+}
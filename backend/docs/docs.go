@@ -15,9 +15,167 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/cache": {
+            "get": {
+                "description": "GET returns hit, miss, eviction and expiration counts and the bytes in use.\nDELETE removes all entries, or only those of the document given by file_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Inspect or flush the search cache",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Document whose entries to remove (DELETE only, default all)",
+                        "name": "file_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/searchCache.Stats"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns hit, miss, eviction and expiration counts and the bytes in use.\nDELETE removes all entries, or only those of the document given by file_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Inspect or flush the search cache",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Document whose entries to remove (DELETE only, default all)",
+                        "name": "file_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/searchCache.Stats"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/autocomplete": {
+            "get": {
+                "description": "Returns words and frequent phrases of the uploaded file that complete the prefix, tolerating one typo",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Complete a search prefix",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File to complete against",
+                        "name": "file_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Typed prefix",
+                        "name": "prefix",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of completions (default 10, max 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/autocomplete.Completion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or limit",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/document": {
+            "get": {
+                "description": "Returns the metadata stored for an uploaded file, such as its detected language and analyzer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get document metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File to describe",
+                        "name": "file_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Metadata"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/expand-context": {
             "post": {
-                "description": "Returns the sentence at the given index from the uploaded file",
+                "description": "Returns the unit (sentence, line, paragraph or window, depending on how the file was chunked) at the given index",
                 "consumes": [
                     "application/json"
                 ],
@@ -43,10 +201,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ExpandContextResponse"
                         }
                     },
                     "400": {
@@ -81,9 +236,101 @@ const docTemplate = `{
                 }
             }
         },
+        "/jobs/{id}": {
+            "get": {
+                "description": "GET returns the state, progress and sentence count of an upload started with async=true. DELETE cancels it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Get or cancel an upload job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID returned by /upload",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/jobs.Status"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Job already finished",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns the state, progress and sentence count of an upload started with async=true. DELETE cancels it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Get or cancel an upload job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID returned by /upload",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/jobs.Status"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Job already finished",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/search": {
             "post": {
-                "description": "Searches the uploaded file with fuzzy matching and returns matched sentences",
+                "description": "Searches the uploaded file with fuzzy matching and returns matched sentences.\nQuery terms are expanded with the synonyms of the requested collection first.\nDocuments uploaded with an analyzer are matched term by term on stemmed words.\nResults from CSV, TSV, JSON and NDJSON files carry the whole record; set field to search a single field.\nIdentical searches running at the same time are computed once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -116,7 +363,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Invalid request or unknown field",
                         "schema": {
                             "type": "string"
                         }
@@ -126,13 +373,67 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "500": {
+                        "description": "Search failed",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/upload": {
+        "/synonyms": {
+            "get": {
+                "description": "POST uploads a Solr-style synonyms file for a collection, replacing any previous list.\nGET returns the names of all collections with synonyms.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "synonyms"
+                ],
+                "summary": "Upload or list synonym collections",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Synonyms file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collection name (default: \\",
+                        "name": "collection",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Synonyms uploaded successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Unable to parse form, retrieve file or parse synonyms",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Uploads a file, splits it into sentences, and stores it for search",
+                "description": "POST uploads a Solr-style synonyms file for a collection, replacing any previous list.\nGET returns the names of all collections with synonyms.",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -140,27 +441,150 @@ const docTemplate = `{
                     "text/plain"
                 ],
                 "tags": [
-                    "upload"
+                    "synonyms"
                 ],
-                "summary": "Upload a text file",
+                "summary": "Upload or list synonym collections",
                 "parameters": [
                     {
                         "type": "file",
-                        "description": "Text file to upload",
+                        "description": "Synonyms file",
                         "name": "file",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collection name (default: \\",
+                        "name": "collection",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "File uploaded successfully",
+                        "description": "Synonyms uploaded successfully",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Unable to parse form or retrieve file",
+                        "description": "Unable to parse form, retrieve file or parse synonyms",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/upload": {
+            "post": {
+                "description": "Uploads a text, PDF, DOCX, ODT, RTF, EPUB, HTML, Markdown, CSV, TSV, JSON or NDJSON file, splits it into sentences (or another chunking unit), and stores it for search.\nZip, tar and tar.gz archives are unpacked and every file is indexed as \"\u003carchive\u003e/\u003cpath\u003e\"; the response is then a JSON report per file. Other gzip-compressed files are decompressed and indexed under their upload name.\nPlain text is indexed while it is streamed. Form fields must precede the file, or be passed as query parameters.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Upload a document",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Document to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text analysis for token-level matching: none (default), standard, english, german, auto (by detected language)",
+                        "name": "analyzer",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also detect the language of every sentence",
+                        "name": "detect_sentences",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search unit: sentence (default), line, paragraph or window",
+                        "name": "chunking",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Characters per window for window chunking (default 200)",
+                        "name": "window_size",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Characters shared by consecutive windows (default 50)",
+                        "name": "window_overlap",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Character encoding of text files: utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1 (detected by default)",
+                        "name": "encoding",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated record fields to index for CSV, TSV, JSON and NDJSON files (default all)",
+                        "name": "fields",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated preprocessing stages run in order: normalize, strip_control, dehyphenate, boilerplate, collapse_whitespace, redact, or none (default set by the server)",
+                        "name": "preprocess",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Respond with a job right away and ingest in the background, see /jobs/{id}",
+                        "name": "async",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report for archive uploads",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ArchiveReport"
+                        }
+                    },
+                    "202": {
+                        "description": "Job for async uploads",
+                        "schema": {
+                            "$ref": "#/definitions/jobs.Status"
+                        }
+                    },
+                    "400": {
+                        "description": "Unable to parse form, retrieve file or invalid options",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload exceeds the size limit, or archive exceeds file count or size limits",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unable to extract text or archive",
                         "schema": {
                             "type": "string"
                         }
@@ -170,12 +594,168 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Job queue is full",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "autocomplete.Completion": {
+            "type": "object",
+            "properties": {
+                "distance": {
+                    "type": "integer"
+                },
+                "frequency": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ArchiveEntryReport": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "file_id": {
+                    "description": "id to search the file by, \"\u003carchive\u003e/\u003cname\u003e\"",
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "path inside the archive",
+                    "type": "string"
+                },
+                "sentences": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "\"indexed\" or \"failed\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ArchiveReport": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ArchiveEntryReport"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "indexed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ExpandContextResponse": {
+            "type": "object",
+            "properties": {
+                "chapter": {
+                    "description": "1-based chapter of EPUB uploads, in reading order",
+                    "type": "integer"
+                },
+                "chapter_title": {
+                    "description": "chapter title from the book's table of contents",
+                    "type": "string"
+                },
+                "context": {
+                    "type": "string"
+                },
+                "field": {
+                    "description": "record field the sentence was taken from",
+                    "type": "string"
+                },
+                "heading": {
+                    "description": "nearest heading at or before the sentence",
+                    "type": "string"
+                },
+                "heading_level": {
+                    "description": "level if the sentence is itself a heading",
+                    "type": "integer"
+                },
+                "length": {
+                    "description": "length of the sentence in bytes",
+                    "type": "integer"
+                },
+                "line": {
+                    "description": "1-based line the sentence starts on",
+                    "type": "integer"
+                },
+                "offset": {
+                    "description": "byte offset of the sentence start",
+                    "type": "integer"
+                },
+                "page": {
+                    "description": "1-based page for paged formats such as PDF",
+                    "type": "integer"
+                },
+                "paragraph": {
+                    "description": "0-based paragraph index",
+                    "type": "integer"
+                },
+                "record": {
+                    "description": "1-based row or object of CSV, TSV, JSON and NDJSON uploads",
+                    "type": "integer"
+                },
+                "unit": {
+                    "description": "chunking unit of the document, e.g. \"sentence\" or \"line\"",
+                    "type": "string"
+                }
+            }
+        },
+        "jobs.Status": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "progress": {
+                    "description": "percentage of the upload processed",
+                    "type": "integer"
+                },
+                "result": {
+                    "description": "outcome reported by the job, e.g. an archive report"
+                },
+                "sentences": {
+                    "type": "integer"
+                },
+                "state": {
+                    "description": "queued, running, done, failed or canceled",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
         "models.ExpandContextRequest": {
             "type": "object",
             "properties": {
@@ -190,9 +770,21 @@ const docTemplate = `{
         "models.SearchRequest": {
             "type": "object",
             "properties": {
+                "collection": {
+                    "description": "synonym collection, \"default\" if empty",
+                    "type": "string"
+                },
+                "field": {
+                    "description": "only match values of this record field",
+                    "type": "string"
+                },
                 "file_id": {
                     "type": "string"
                 },
+                "language": {
+                    "description": "only match sentences in this language (ISO 639-1)",
+                    "type": "string"
+                },
                 "query": {
                     "type": "string"
                 }
@@ -201,17 +793,189 @@ const docTemplate = `{
         "search.SearchResult": {
             "type": "object",
             "properties": {
+                "chapter": {
+                    "description": "1-based chapter of EPUB uploads, in reading order",
+                    "type": "integer"
+                },
+                "chapter_title": {
+                    "description": "chapter title from the book's table of contents",
+                    "type": "string"
+                },
                 "distance": {
                     "type": "integer"
                 },
+                "expansion": {
+                    "description": "synonym rule that produced the match",
+                    "type": "string"
+                },
+                "field": {
+                    "description": "record field the sentence was taken from",
+                    "type": "string"
+                },
+                "heading": {
+                    "description": "nearest heading at or before the sentence",
+                    "type": "string"
+                },
+                "heading_level": {
+                    "description": "level if the sentence is itself a heading",
+                    "type": "integer"
+                },
                 "index": {
                     "type": "integer"
                 },
+                "length": {
+                    "description": "length of the sentence in bytes",
+                    "type": "integer"
+                },
+                "line": {
+                    "description": "1-based line the sentence starts on",
+                    "type": "integer"
+                },
                 "match": {
                     "type": "string"
                 },
+                "offset": {
+                    "description": "byte offset of the sentence start",
+                    "type": "integer"
+                },
+                "page": {
+                    "description": "1-based page for paged formats such as PDF",
+                    "type": "integer"
+                },
+                "paragraph": {
+                    "description": "0-based paragraph index",
+                    "type": "integer"
+                },
+                "record": {
+                    "description": "1-based row or object of CSV, TSV, JSON and NDJSON uploads",
+                    "type": "integer"
+                },
                 "sentence": {
                     "type": "string"
+                },
+                "sentence_index": {
+                    "type": "integer"
+                },
+                "values": {
+                    "description": "fields of the original record for structured formats",
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "searchCache.Stats": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "description": "estimated size of the entries, including pending ones",
+                    "type": "integer"
+                },
+                "coalesced": {
+                    "description": "misses that waited for an identical search in flight",
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "evictions": {
+                    "description": "entries removed to make room for others",
+                    "type": "integer"
+                },
+                "expirations": {
+                    "description": "entries removed after their TTL",
+                    "type": "integer"
+                },
+                "hit_rate": {
+                    "description": "hits per lookup, 0 without lookups",
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "max_bytes": {
+                    "description": "size budget",
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "pending": {
+                    "description": "loaded entries waiting for their document to be uploaded again",
+                    "type": "integer"
+                },
+                "policy": {
+                    "description": "eviction policy, see Policies",
+                    "type": "string"
+                },
+                "rejections": {
+                    "description": "new entries the eviction policy did not admit",
+                    "type": "integer"
+                },
+                "shards": {
+                    "type": "integer"
+                },
+                "ttl": {
+                    "description": "default TTL of entries, empty if they do not expire",
+                    "type": "string"
+                }
+            }
+        },
+        "storage.Metadata": {
+            "type": "object",
+            "properties": {
+                "analyzer": {
+                    "description": "applied at index and query time, \"\" for plain fuzzy matching",
+                    "type": "string"
+                },
+                "chunking": {
+                    "description": "unit the document is split into, see utils.Chunk*",
+                    "type": "string"
+                },
+                "encoding": {
+                    "description": "character encoding of text formats, see charset",
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "indexed record fields",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "format": {
+                    "description": "detected file format, see extract.Format*",
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "SHA-256 of the searchable content, set by AddDocument",
+                    "type": "string"
+                },
+                "language": {
+                    "description": "dominant language, ISO 639-1",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "preprocessing": {
+                    "description": "preprocessing stages run in order, see preprocess",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "records": {
+                    "description": "rows or objects of structured formats",
+                    "type": "integer"
+                },
+                "sentences": {
+                    "type": "integer"
+                },
+                "window_overlap": {
+                    "type": "integer"
+                },
+                "window_size": {
+                    "type": "integer"
                 }
             }
         }
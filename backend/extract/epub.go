@@ -0,0 +1,225 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// epubContainer is META-INF/container.xml, which points to the package document
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the OPF package document listing the book's files and
+// their reading order
+type epubPackage struct {
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// epubNCX is the EPUB 2 table of contents
+type epubNCX struct {
+	NavPoints []epubNavPoint `xml:"navMap>navPoint"`
+}
+
+type epubNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []epubNavPoint `xml:"navPoint"`
+}
+
+// extractEPUB returns the blocks of every chapter in spine order, tagged
+// with the chapter number and the title from the table of contents
+func extractEPUB(data []byte) (*Result, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := readZipFile(zr, "META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(raw, &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("missing package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	raw, err = readZipFile(zr, opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(raw, &pkg); err != nil {
+		return nil, err
+	}
+
+	base := path.Dir(opfPath)
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	mediaTypes := make(map[string]string, len(pkg.Manifest))
+	titles := map[string]string{}
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = epubPath(base, item.Href)
+		mediaTypes[item.ID] = item.MediaType
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			epubNavTitles(zr, hrefs[item.ID], titles)
+		}
+	}
+	if len(titles) == 0 && pkg.Spine.Toc != "" {
+		epubNCXTitles(zr, hrefs[pkg.Spine.Toc], titles)
+	}
+
+	// Spine items that are missing or unreadable are skipped, the book
+	// only fails if none of them could be read
+	result := &Result{Format: FormatEPUB}
+	chapter := 0
+	var skipped error
+	for _, ref := range pkg.Spine.Itemrefs {
+		switch mediaTypes[ref.IDRef] {
+		case "application/xhtml+xml", "text/html":
+		default:
+			continue
+		}
+		content, err := readZipFile(zr, hrefs[ref.IDRef])
+		if err != nil {
+			skipped = errors.Join(skipped, err)
+			continue
+		}
+		doc, err := extractHTML(content)
+		if err != nil {
+			skipped = errors.Join(skipped, err)
+			continue
+		}
+		if len(doc.Blocks) == 0 {
+			continue
+		}
+
+		chapter++
+		title := titles[hrefs[ref.IDRef]]
+		if title == "" {
+			title = firstHeading(doc.Blocks)
+		}
+		for _, b := range doc.Blocks {
+			b.Chapter = chapter
+			b.ChapterTitle = title
+			result.Blocks = append(result.Blocks, b)
+		}
+	}
+	if chapter == 0 && skipped != nil {
+		return nil, skipped
+	}
+	return result, nil
+}
+
+// epubPath resolves a manifest or table of contents link to a zip entry name
+func epubPath(base, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Clean(path.Join(base, href))
+}
+
+// epubNavTitles reads chapter titles from the toc nav of an EPUB 3
+// navigation document. The first entry linking to a file names it.
+func epubNavTitles(zr *zip.Reader, name string, titles map[string]string) {
+	raw, err := readZipFile(zr, name)
+	if err != nil {
+		return
+	}
+	root, err := html.Parse(bytes.NewReader(raw))
+	if err != nil {
+		return
+	}
+
+	var walk func(n *html.Node, inToc bool)
+	walk = func(n *html.Node, inToc bool) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Nav {
+			inToc = htmlAttr(n, "epub:type") == "toc" || htmlAttr(n, "role") == "doc-toc"
+		}
+		if inToc && n.Type == html.ElementNode && n.DataAtom == atom.A {
+			target := epubPath(path.Dir(name), htmlAttr(n, "href"))
+			if title := strings.Join(strings.Fields(nodeText(n)), " "); title != "" && titles[target] == "" {
+				titles[target] = title
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inToc)
+		}
+	}
+	walk(root, false)
+}
+
+// epubNCXTitles reads chapter titles from an EPUB 2 NCX file
+func epubNCXTitles(zr *zip.Reader, name string, titles map[string]string) {
+	raw, err := readZipFile(zr, name)
+	if err != nil {
+		return
+	}
+	var ncx epubNCX
+	if err := xml.Unmarshal(raw, &ncx); err != nil {
+		return
+	}
+
+	var walk func(points []epubNavPoint)
+	walk = func(points []epubNavPoint) {
+		for _, p := range points {
+			target := epubPath(path.Dir(name), p.Content.Src)
+			if title := strings.Join(strings.Fields(p.Label), " "); title != "" && titles[target] == "" {
+				titles[target] = title
+			}
+			walk(p.Children)
+		}
+	}
+	walk(ncx.NavPoints)
+}
+
+// nodeText returns the text content of an HTML node
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
+
+// firstHeading returns the text of the first heading block, if any
+func firstHeading(blocks []Block) string {
+	for _, b := range blocks {
+		if b.Level > 0 {
+			return b.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// buildZip writes the given entries, in order, into a zip archive
+func buildZip(entries ...[2]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, _ := zw.Create(e[0])
+		w.Write([]byte(e[1]))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractEPUB(t *testing.T) {
+	container := `<?xml version="1.0"?>
+<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container" version="1.0">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+	opf := `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="c1" href="text/one.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/two%20b.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover" href="cover.jpg" media-type="image/jpeg"/>
+  </manifest>
+  <spine><itemref idref="c2"/><itemref idref="cover"/><itemref idref="c1"/></spine>
+</package>`
+	nav := `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="toc"><ol>
+  <li><a href="text/one.xhtml">The  Beginning</a></li>
+  <li><a href="text/two%20b.xhtml#start">Later On</a></li>
+</ol></nav></body></html>`
+	one := `<html><body><h1>Chapter One</h1><p>It was a dark night.</p></body></html>`
+	two := `<html><body><p>Morning came.</p></body></html>`
+
+	data := buildZip(
+		[2]string{"mimetype", "application/epub+zip"},
+		[2]string{"META-INF/container.xml", container},
+		[2]string{"OEBPS/content.opf", opf},
+		[2]string{"OEBPS/nav.xhtml", nav},
+		[2]string{"OEBPS/text/one.xhtml", one},
+		[2]string{"OEBPS/text/two b.xhtml", two},
+	)
+
+	result, err := Extract("book.epub", data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if result.Format != FormatEPUB {
+		t.Errorf("Expected format %q, got %q", FormatEPUB, result.Format)
+	}
+
+	want := []Block{
+		{Text: "Morning came.", Chapter: 1, ChapterTitle: "Later On"},
+		{Text: "Chapter One", Heading: "Chapter One", Level: 1, Chapter: 2, ChapterTitle: "The Beginning"},
+		{Text: "It was a dark night.", Heading: "Chapter One", Chapter: 2, ChapterTitle: "The Beginning"},
+	}
+	if len(result.Blocks) != len(want) {
+		t.Fatalf("Expected %d blocks, got %+v", len(want), result.Blocks)
+	}
+	for i := range want {
+		if result.Blocks[i] != want[i] {
+			t.Errorf("Block %d: expected %+v, got %+v", i, want[i], result.Blocks[i])
+		}
+	}
+}
+
+func TestExtractEPUBWithNCX(t *testing.T) {
+	data := buildZip(
+		[2]string{"mimetype", "application/epub+zip"},
+		[2]string{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="book.opf"/></rootfiles></container>`},
+		[2]string{"book.opf", `<package><manifest>
+  <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+  <item id="c1" href="c1.html" media-type="application/xhtml+xml"/>
+  <item id="c2" href="c2.html" media-type="application/xhtml+xml"/>
+</manifest><spine toc="ncx"><itemref idref="c1"/><itemref idref="c2"/></spine></package>`},
+		[2]string{"toc.ncx", `<ncx><navMap><navPoint><navLabel><text>Prologue</text></navLabel><content src="c1.html"/></navPoint></navMap></ncx>`},
+		[2]string{"c1.html", `<html><body><p>First words.</p></body></html>`},
+		[2]string{"c2.html", `<html><body><h2>Untitled in TOC</h2><p>Second words.</p></body></html>`},
+	)
+
+	result, err := Extract("book.epub", data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	titles := []string{"Prologue", "Untitled in TOC", "Untitled in TOC"}
+	if len(result.Blocks) != len(titles) {
+		t.Fatalf("Expected %d blocks, got %+v", len(titles), result.Blocks)
+	}
+	for i, title := range titles {
+		if result.Blocks[i].ChapterTitle != title {
+			t.Errorf("Block %d: expected chapter title %q, got %q", i, title, result.Blocks[i].ChapterTitle)
+		}
+	}
+}
+
+func TestExtractEPUBMissingItem(t *testing.T) {
+	container := `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`
+	opf := `<package>
+  <manifest>
+    <item id="c1" href="one.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="missing.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c3" href="three.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine><itemref idref="c1"/><itemref idref="c2"/><itemref idref="c3"/></spine>
+</package>`
+
+	data := buildZip(
+		[2]string{"mimetype", "application/epub+zip"},
+		[2]string{"META-INF/container.xml", container},
+		[2]string{"content.opf", opf},
+		[2]string{"one.xhtml", `<html><body><p>First.</p></body></html>`},
+		[2]string{"three.xhtml", `<html><body><p>Third.</p></body></html>`},
+	)
+	result, err := Extract("book.epub", data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	want := []Block{
+		{Text: "First.", Chapter: 1},
+		{Text: "Third.", Chapter: 2},
+	}
+	if len(result.Blocks) != len(want) || result.Blocks[0] != want[0] || result.Blocks[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, result.Blocks)
+	}
+
+	// A book without any readable chapter still fails
+	data = buildZip(
+		[2]string{"mimetype", "application/epub+zip"},
+		[2]string{"META-INF/container.xml", container},
+		[2]string{"content.opf", opf},
+	)
+	if _, err := Extract("book.epub", data); err == nil {
+		t.Errorf("Expected error for a book without chapters")
+	}
+}
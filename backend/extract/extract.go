@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Formats recognized by Extract
+const (
+	FormatText     = "text"
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatODT      = "odt"
+	FormatRTF      = "rtf"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatEPUB     = "epub"
+)
+
+// ErrUnsupported is returned for content that cannot be turned into text
+var ErrUnsupported = errors.New("unsupported file format")
+
+// Block is a run of extracted text that segmentation must not cross, such
+// as a page or a paragraph, together with where it came from
+type Block struct {
+	Text    string
+	Page    int    // 1-based page number, 0 if the format has no pages
+	Heading string // text of the nearest heading at or before this block
+	Level   int    // heading level if the block is a heading, 0 otherwise
+	Record  int    // 1-based record of structured formats, 0 otherwise
+	Field   string // name of the record field holding the text
+
+	Chapter      int    // 1-based chapter in reading order for books, 0 otherwise
+	ChapterTitle string // title of the chapter from the table of contents
+}
+
+// Result is the text content of an uploaded file
+type Result struct {
+	Format  string
+	Blocks  []Block
+	Records []map[string]any // rows or objects of structured formats, by field name
+}
+
+// Extract sniffs the content of an uploaded file and returns its text
+func Extract(filename string, data []byte) (*Result, error) {
+	switch Sniff(filename, data) {
+	case FormatPDF:
+		return extractPDF(data)
+	case FormatDOCX:
+		return extractDOCX(data)
+	case FormatODT:
+		return extractODT(data)
+	case FormatRTF:
+		return extractRTF(data)
+	case FormatHTML:
+		return extractHTML(data)
+	case FormatMarkdown:
+		return extractMarkdown(data)
+	case FormatEPUB:
+		return extractEPUB(data)
+	case FormatCSV:
+		return extractCSV(data, ',', FormatCSV)
+	case FormatTSV:
+		return extractCSV(data, '\t', FormatTSV)
+	case FormatJSON:
+		return extractJSON(data)
+	case FormatNDJSON:
+		return extractNDJSON(data)
+	default:
+		return &Result{Format: FormatText, Blocks: []Block{{Text: string(data)}}}, nil
+	}
+}
+
+// IsText reports whether a format is plain text underneath, so its bytes
+// must be transcoded to UTF-8 before extraction
+func IsText(format string) bool {
+	switch format {
+	case FormatText, FormatHTML, FormatMarkdown, FormatCSV, FormatTSV, FormatJSON, FormatNDJSON:
+		return true
+	}
+	return false
+}
+
+// Sniff detects the format of a file from its extension for markup and
+// structured formats and from its leading bytes otherwise
+func Sniff(filename string, data []byte) string {
+	head := data[:min(len(data), 1024)]
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".md", ".markdown", ".mdown":
+		return FormatMarkdown
+	case ".csv":
+		return FormatCSV
+	case ".tsv", ".tab":
+		return FormatTSV
+	case ".json":
+		return FormatJSON
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+	switch {
+	case bytes.Contains(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(head, []byte("{\\rtf")):
+		return FormatRTF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		if format := zipFormat(data); format != "" {
+			return format
+		}
+	case isHTML(head):
+		return FormatHTML
+	case isJSON(head, data):
+		return FormatJSON
+	}
+	return FormatText
+}
@@ -0,0 +1,235 @@
+package extract
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlSkipped elements never contain document text
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Head: true, atom.Svg: true, atom.Math: true, atom.Iframe: true,
+	atom.Object: true, atom.Canvas: true, atom.Select: true, atom.Button: true,
+}
+
+// htmlBlocks are elements that start and end a block of text
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Li: true, atom.Dt: true, atom.Dd: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Td: true, atom.Th: true, atom.Tr: true, atom.Table: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Header: true, atom.Footer: true,
+	atom.Nav: true, atom.Aside: true, atom.Main: true, atom.Figure: true,
+	atom.Figcaption: true, atom.Form: true, atom.Address: true, atom.Hr: true,
+	atom.Caption: true, atom.Details: true, atom.Summary: true, atom.Body: true,
+}
+
+var htmlHeadings = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// isHTML reports whether data starts like an HTML document
+func isHTML(head []byte) bool {
+	head = bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
+
+// extractHTML returns one block per block-level element or heading, without
+// tags, attributes, scripts or styles
+func extractHTML(data []byte) (*Result, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Format: FormatHTML}
+	w := &htmlWalker{result: result}
+	w.walk(root)
+	w.flush()
+	assignHeadings(result.Blocks)
+	return result, nil
+}
+
+// htmlWalker collects the text of the current block while walking the DOM
+type htmlWalker struct {
+	result *Result
+	text   strings.Builder
+	level  int
+	pre    int // depth of enclosing <pre> elements
+}
+
+func (w *htmlWalker) flush() {
+	text := w.text.String()
+	if w.pre == 0 {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	w.result.Blocks = appendParagraph(w.result.Blocks, text, w.level)
+	w.text.Reset()
+	w.level = 0
+}
+
+func (w *htmlWalker) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if htmlSkipped[n.DataAtom] {
+			return
+		}
+		if n.DataAtom == atom.Br {
+			w.text.WriteByte('\n')
+			return
+		}
+		if n.DataAtom == atom.Img {
+			// Alt text is the only visible text of an image
+			if alt := htmlAttr(n, "alt"); alt != "" {
+				w.text.WriteString(" " + alt + " ")
+			}
+			return
+		}
+	}
+
+	level, isHeading := htmlHeadings[n.DataAtom]
+	isBlock := isHeading || htmlBlocks[n.DataAtom]
+	if isBlock {
+		w.flush()
+	}
+	if n.DataAtom == atom.Pre {
+		w.pre++
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+	if isBlock {
+		w.level = level
+		w.flush()
+	}
+	if n.DataAtom == atom.Pre {
+		w.pre--
+	}
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+var (
+	mdATXHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdSetext       = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	mdFence        = regexp.MustCompile("^ {0,3}(```|~~~)")
+	mdListItem     = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	mdRule         = regexp.MustCompile(`^ {0,3}([-*_])(?:\s*([-*_])){2,}\s*$`)
+	mdTableDivider = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdRefDef       = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s+\S+`)
+	mdImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink         = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink     = regexp.MustCompile(`<((?:https?|mailto):[^>]+)>`)
+	mdTag          = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdEmphasis     = regexp.MustCompile(`(\*\*|__|~~)(\S(?:.*?\S)?)(\*\*|__|~~)`)
+	mdItalic       = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:.*?\S)?)[*_]([^\w*]|$)`)
+	mdCode         = regexp.MustCompile("`+([^`]*)`+")
+	mdEscape       = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!|>~])`)
+)
+
+// extractMarkdown returns one block per paragraph, list item, heading or
+// code block, with inline markup removed
+func extractMarkdown(data []byte) (*Result, error) {
+	result := &Result{Format: FormatMarkdown}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	lines = skipFrontMatter(lines)
+
+	var para []string
+	flush := func(level int) {
+		result.Blocks = appendParagraph(result.Blocks, strings.Join(para, "\n"), level)
+		para = para[:0]
+	}
+
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				flush(0)
+				fence = ""
+			} else {
+				para = append(para, line)
+			}
+			continue
+		}
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			flush(0)
+			fence = m[1]
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush(0)
+		case mdRule.MatchString(line) && len(para) == 0:
+			flush(0)
+		case mdSetext.MatchString(line) && len(para) > 0:
+			level := 1
+			if strings.HasPrefix(trimmed, "-") {
+				level = 2
+			}
+			flush(level)
+		case mdATXHeading.MatchString(line):
+			flush(0)
+			m := mdATXHeading.FindStringSubmatch(line)
+			para = append(para, stripInlineMarkdown(m[2]))
+			flush(len(m[1]))
+		case mdRefDef.MatchString(line), mdTableDivider.MatchString(line) && strings.Contains(line, "-"):
+			// Link reference definitions and table dividers hold no text
+		case mdListItem.MatchString(line):
+			flush(0)
+			para = append(para, stripInlineMarkdown(mdListItem.ReplaceAllString(line, "")))
+		default:
+			text := strings.TrimLeft(trimmed, "> ")
+			if strings.HasPrefix(text, "|") {
+				text = strings.Join(strings.Fields(strings.ReplaceAll(strings.Trim(text, "|"), "|", " ")), " ")
+			}
+			para = append(para, stripInlineMarkdown(text))
+		}
+	}
+	flush(0)
+	assignHeadings(result.Blocks)
+	return result, nil
+}
+
+// skipFrontMatter drops a leading YAML front matter block
+func skipFrontMatter(lines []string) []string {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return lines
+	}
+	for i := 1; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); t == "---" || t == "..." {
+			return lines[i+1:]
+		}
+	}
+	return lines
+}
+
+// stripInlineMarkdown removes links, images, emphasis, code spans and tags
+func stripInlineMarkdown(s string) string {
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdAutolink.ReplaceAllString(s, "$1")
+	s = mdTag.ReplaceAllString(s, "")
+	s = mdCode.ReplaceAllString(s, "$1")
+	for i := 0; i < 2; i++ {
+		s = mdEmphasis.ReplaceAllString(s, "$2")
+		s = mdItalic.ReplaceAllString(s, "$1$2$3")
+	}
+	s = mdEscape.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,97 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		input    string
+		format   string
+		blocks   []Block
+	}{
+		{
+			name:     "HTML document",
+			filename: "page.html",
+			input: `<!DOCTYPE html><html><head><title>Ignored</title><style>p{color:red}</style></head>
+<body><h1>Getting  started</h1><p>Install the <b>tool</b>.
+Then <a href="/run">run</a> it.</p><script>alert("x")</script>
+<ul><li>First step</li><li>Second <img alt="diagram"> step</li></ul>
+<h2>Usage</h2><pre>go run
+  main.go</pre></body></html>`,
+			format: FormatHTML,
+			blocks: []Block{
+				{Text: "Getting started", Heading: "Getting started", Level: 1},
+				{Text: "Install the tool. Then run it.", Heading: "Getting started"},
+				{Text: "First step", Heading: "Getting started"},
+				{Text: "Second diagram step", Heading: "Getting started"},
+				{Text: "Usage", Heading: "Usage", Level: 2},
+				{Text: "go run\n  main.go", Heading: "Usage"},
+			},
+		},
+		{
+			name:     "HTML sniffed without extension",
+			filename: "upload",
+			input:    "<html><body><p>Plain &amp; simple</p></body></html>",
+			format:   FormatHTML,
+			blocks:   []Block{{Text: "Plain & simple"}},
+		},
+		{
+			name:     "Markdown document",
+			filename: "README.md",
+			input: `---
+title: ignored
+---
+# Fuzzy *search*
+
+Upload a [file](http://example.com) and **search** it.
+Use ` + "`go test`" + ` to check.
+
+Details
+-------
+
+- First item
+- Second ![logo](logo.png) item
+
+> Quoted text
+
+| Name | Value |
+|------|-------|
+| a    | 1     |
+
+` + "```go\nfmt.Println(\"hi\")\n```" + `
+
+[ref]: http://example.com
+`,
+			format: FormatMarkdown,
+			blocks: []Block{
+				{Text: "Fuzzy search", Heading: "Fuzzy search", Level: 1},
+				{Text: "Upload a file and search it.\nUse go test to check.", Heading: "Fuzzy search"},
+				{Text: "Details", Heading: "Details", Level: 2},
+				{Text: "First item", Heading: "Details"},
+				{Text: "Second logo item", Heading: "Details"},
+				{Text: "Quoted text", Heading: "Details"},
+				{Text: "Name Value\na 1", Heading: "Details"},
+				{Text: "fmt.Println(\"hi\")", Heading: "Details"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Extract(tt.filename, []byte(tt.input))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if result.Format != tt.format {
+				t.Errorf("Expected format %q, got %q", tt.format, result.Format)
+			}
+			if !reflect.DeepEqual(result.Blocks, tt.blocks) {
+				t.Errorf("Test %q failed. Got: %+v", tt.name, result.Blocks)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxXMLPartSize caps how much of a single zip entry is read
+const maxXMLPartSize = 64 << 20
+
+var docxHeadingStyle = regexp.MustCompile(`(?i)^(heading|berschrift|titre|titolo)\s*([1-9])$`)
+
+// openZip opens data as a zip archive
+func openZip(data []byte) (*zip.Reader, error) {
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// readZipFile returns the content of the named entry
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxXMLPartSize))
+	}
+	return nil, errors.New("missing " + name)
+}
+
+// zipFormat tells DOCX, ODT, EPUB and other zip based formats apart
+func zipFormat(data []byte) string {
+	zr, err := openZip(data)
+	if err != nil {
+		return ""
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return FormatDOCX
+		}
+	}
+	if mimetype, err := readZipFile(zr, "mimetype"); err == nil {
+		switch strings.TrimSpace(string(mimetype)) {
+		case "application/vnd.oasis.opendocument.text":
+			return FormatODT
+		case "application/epub+zip":
+			return FormatEPUB
+		}
+	}
+	return ""
+}
+
+// extractDOCX returns one block per paragraph of word/document.xml, with
+// heading levels taken from "HeadingN" styles or outline levels
+func extractDOCX(data []byte) (*Result, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Format: FormatDOCX}
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var text strings.Builder
+	level := 0
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				text.Reset()
+				level = 0
+			case "pStyle":
+				if m := docxHeadingStyle.FindStringSubmatch(attr(t, "val")); m != nil {
+					level, _ = strconv.Atoi(m[2])
+				} else if strings.EqualFold(attr(t, "val"), "Title") {
+					level = 1
+				}
+			case "outlineLvl":
+				if n, err := strconv.Atoi(attr(t, "val")); err == nil && n < 9 && level == 0 {
+					level = n + 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				result.Blocks = appendParagraph(result.Blocks, text.String(), level)
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	assignHeadings(result.Blocks)
+	return result, nil
+}
+
+// extractODT returns one block per text:p and text:h of content.xml
+func extractODT(data []byte) (*Result, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readZipFile(zr, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Format: FormatODT}
+	dec := xml.NewDecoder(bytes.NewReader(content))
+
+	// Paragraphs can nest, e.g. inside footnotes, so keep a stack
+	type paragraph struct {
+		text  strings.Builder
+		level int
+	}
+	var stack []*paragraph
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var top *paragraph
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				stack = append(stack, &paragraph{})
+			case "h":
+				level, err := strconv.Atoi(attr(t, "outline-level"))
+				if err != nil || level < 1 {
+					level = 1
+				}
+				stack = append(stack, &paragraph{level: level})
+			case "s":
+				if top != nil {
+					n, err := strconv.Atoi(attr(t, "c"))
+					if err != nil || n < 1 {
+						n = 1
+					}
+					top.text.WriteString(strings.Repeat(" ", n))
+				}
+			case "tab":
+				if top != nil {
+					top.text.WriteByte('\t')
+				}
+			case "line-break":
+				if top != nil {
+					top.text.WriteByte('\n')
+				}
+			}
+		case xml.EndElement:
+			if (t.Name.Local == "p" || t.Name.Local == "h") && top != nil {
+				stack = stack[:len(stack)-1]
+				result.Blocks = appendParagraph(result.Blocks, top.text.String(), top.level)
+			}
+		case xml.CharData:
+			if top != nil {
+				top.text.Write(t)
+			}
+		}
+	}
+	assignHeadings(result.Blocks)
+	return result, nil
+}
+
+// appendParagraph adds a non-empty paragraph as a block
+func appendParagraph(blocks []Block, text string, level int) []Block {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return blocks
+	}
+	return append(blocks, Block{Text: text, Level: level})
+}
+
+// assignHeadings sets Heading of every block to the nearest preceding heading
+func assignHeadings(blocks []Block) {
+	heading := ""
+	for i := range blocks {
+		if blocks[i].Level > 0 {
+			heading = blocks[i].Text
+		}
+		if blocks[i].Heading == "" {
+			blocks[i].Heading = heading
+		}
+	}
+}
+
+// attr returns the value of the attribute with the given local name
+func attr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
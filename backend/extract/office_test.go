@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtractOfficeFormats(t *testing.T) {
+	tests := []struct {
+		fixture string
+		format  string
+		blocks  []Block
+	}{
+		{
+			fixture: "sample.docx",
+			format:  FormatDOCX,
+			blocks: []Block{
+				{Text: "Installation", Heading: "Installation", Level: 1},
+				{Text: "Run the installer first. Then restart.", Heading: "Installation"},
+				{Text: "Configuration", Heading: "Configuration", Level: 2},
+				{Text: "Edit\tconfig.yaml\nSave it.", Heading: "Configuration"},
+				{Text: "Cell text.", Heading: "Configuration"},
+			},
+		},
+		{
+			fixture: "sample.odt",
+			format:  FormatODT,
+			blocks: []Block{
+				{Text: "Einleitung", Heading: "Einleitung", Level: 1},
+				{Text: "Das ist ein Test.  Zweiter Satz.", Heading: "Einleitung"},
+				{Text: "Details", Heading: "Details", Level: 2},
+				{Text: "Fußnote.", Heading: "Details"},
+				{Text: "Zeile eins\nZeile zwei", Heading: "Details"},
+			},
+		},
+		{
+			fixture: "sample.rtf",
+			format:  FormatRTF,
+			blocks: []Block{
+				{Text: "Overview", Heading: "Overview", Level: 1},
+				{Text: "Café prices rose € 3 today. They fell\ntomorrow.", Heading: "Overview"},
+				{Text: "Notes", Heading: "Notes", Level: 2},
+				{Text: "Use {braces} and a \\ backslash.", Heading: "Notes"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + tt.fixture)
+			if err != nil {
+				t.Fatalf("Reading fixture failed: %v", err)
+			}
+			result, err := Extract(tt.fixture, data)
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if result.Format != tt.format {
+				t.Errorf("Expected format %q, got %q", tt.format, result.Format)
+			}
+			if len(result.Blocks) != len(tt.blocks) {
+				t.Fatalf("Expected %d blocks, got %+v", len(tt.blocks), result.Blocks)
+			}
+			for i := range tt.blocks {
+				if result.Blocks[i] != tt.blocks[i] {
+					t.Errorf("Block %d: expected %+v, got %+v", i, tt.blocks[i], result.Blocks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractRTFUnicodeFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		rtf  string
+		want string
+	}{
+		{"one fallback character", `{\rtf1 Caf\u233?s}`, "Cafés"},
+		{"hex escape fallbacks", `{\rtf1\uc2 Caf\u233\'65\'3fs}`, "Cafés"},
+		{"control word ends fallback", `{\rtf1\uc2 Caf\u233\'65\par s}`, "Café\ns"},
+		{"consecutive characters", `{\rtf1\uc1 \u20013\u25991?}`, "中文"},
+		{"surrogate pair", `{\rtf1 Hi \u-10179?\u-8704?!}`, "Hi 😀!"},
+		{"surrogate pair without fallback", `{\rtf1\uc0 \u55362\u57271}`, "𠮷"},
+		{"lone high surrogate", `{\rtf1 a\u55357?b}`, "a\uFFFDb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Extract("doc.rtf", []byte(tt.rtf))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			var texts []string
+			for _, b := range result.Blocks {
+				texts = append(texts, b.Text)
+			}
+			if got := strings.Join(texts, "\n"); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,828 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// This file implements just enough of PDF to pull text out of pages: objects
+// are located by scanning for "N G obj" rather than trusting the xref table,
+// object streams and the common stream filters are decoded, and content
+// streams are interpreted for their text showing operators.
+
+// pdfName, pdfString, pdfRef etc. are the PDF object types
+type (
+	pdfName   string
+	pdfString []byte
+	pdfArray  []any
+	pdfDict   map[string]any
+	pdfRef    struct{ num, gen int }
+	pdfStream struct {
+		dict pdfDict
+		data []byte
+	}
+	pdfKeyword string
+)
+
+// maxPageDepth guards against cyclic page trees
+const maxPageDepth = 32
+
+// maxObjectDepth caps how deeply arrays and dictionaries nest, so that
+// parsing them recursively cannot exhaust the stack
+const maxObjectDepth = 256
+
+// maxStreamSize caps the decompressed size of a single stream
+const maxStreamSize = 64 << 20
+
+var errObjectDepth = fmt.Errorf("pdf: objects nested more than %d levels deep", maxObjectDepth)
+
+var objHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfFile holds every object of a PDF by object number
+type pdfFile struct {
+	objects map[int]any
+}
+
+// extractPDF returns one block per page
+func extractPDF(data []byte) (*Result, error) {
+	f := &pdfFile{objects: make(map[int]any)}
+	f.loadObjects(data)
+
+	catalog := f.findCatalog()
+	if catalog == nil {
+		return nil, errors.New("pdf: no document catalog")
+	}
+	pagesRoot, _ := f.resolve(catalog["Pages"]).(pdfDict)
+	if pagesRoot == nil {
+		return nil, errors.New("pdf: no page tree")
+	}
+
+	var pages []pdfDict
+	seen := map[int]bool{}
+	if ref, ok := catalog["Pages"].(pdfRef); ok {
+		seen[ref.num] = true
+	}
+	f.collectPages(pagesRoot, nil, &pages, 0, seen)
+
+	result := &Result{Format: FormatPDF}
+	for i, page := range pages {
+		text := f.pageText(page)
+		result.Blocks = append(result.Blocks, Block{Text: text, Page: i + 1})
+	}
+	return result, nil
+}
+
+// loadObjects parses every indirect object, later definitions winning as
+// in incremental updates, then unpacks object streams
+func (f *pdfFile) loadObjects(data []byte) {
+	for _, m := range objHeader.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		lex := &pdfLexer{data: data, pos: m[1]}
+		obj, err := lex.object()
+		if err != nil {
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok {
+			if stream, ok := lex.stream(dict, f); ok {
+				obj = stream
+			}
+		}
+		f.objects[num] = obj
+	}
+
+	for _, obj := range f.objects {
+		stream, ok := obj.(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		f.loadObjectStream(stream)
+	}
+}
+
+// loadObjectStream adds the objects compressed in an object stream
+func (f *pdfFile) loadObjectStream(stream *pdfStream) {
+	data, err := f.decode(stream)
+	if err != nil {
+		return
+	}
+	n, _ := f.resolve(stream.dict["N"]).(float64)
+	first, _ := f.resolve(stream.dict["First"]).(float64)
+	if first < 0 || first > float64(len(data)) {
+		return
+	}
+
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(n); i++ {
+		num, err1 := header.object()
+		off, err2 := header.object()
+		numF, ok1 := num.(float64)
+		offF, ok2 := off.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, exists := f.objects[int(numF)]; exists {
+			continue
+		}
+		if offF < 0 || offF >= float64(len(data))-first {
+			continue
+		}
+		lex := &pdfLexer{data: data, pos: int(first) + int(offF)}
+		if obj, err := lex.object(); err == nil {
+			f.objects[int(numF)] = obj
+		}
+	}
+}
+
+// findCatalog returns the /Type /Catalog dictionary
+func (f *pdfFile) findCatalog() pdfDict {
+	for _, obj := range f.objects {
+		if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			return dict
+		}
+	}
+	return nil
+}
+
+// resolve follows indirect references
+func (f *pdfFile) resolve(obj any) any {
+	for i := 0; i < 8; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.objects[ref.num]
+	}
+	return nil
+}
+
+// collectPages walks the page tree in order, passing inherited resources
+// down. Objects in seen were visited already, so that nodes which are their
+// own descendants or are listed repeatedly are walked once.
+func (f *pdfFile) collectPages(node pdfDict, resources any, pages *[]pdfDict, depth int, seen map[int]bool) {
+	if depth > maxPageDepth {
+		return
+	}
+	if r, ok := node["Resources"]; ok {
+		resources = r
+	}
+	if node["Type"] == pdfName("Page") || node["Kids"] == nil {
+		page := pdfDict{}
+		for k, v := range node {
+			page[k] = v
+		}
+		page["Resources"] = resources
+		*pages = append(*pages, page)
+		return
+	}
+	kids, _ := f.resolve(node["Kids"]).(pdfArray)
+	for _, kid := range kids {
+		if ref, ok := kid.(pdfRef); ok {
+			if seen[ref.num] {
+				continue
+			}
+			seen[ref.num] = true
+		}
+		if child, ok := f.resolve(kid).(pdfDict); ok {
+			f.collectPages(child, resources, pages, depth+1, seen)
+		}
+	}
+}
+
+// pageText decodes the content streams of a page and runs them
+func (f *pdfFile) pageText(page pdfDict) string {
+	var content []byte
+	switch c := f.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = f.decode(c)
+	case pdfArray:
+		for _, part := range c {
+			if s, ok := f.resolve(part).(*pdfStream); ok {
+				data, _ := f.decode(s)
+				content = append(content, data...)
+				content = append(content, '\n')
+			}
+		}
+	}
+
+	fonts := map[string]*pdfFont{}
+	if res, ok := f.resolve(page["Resources"]).(pdfDict); ok {
+		if fontDict, ok := f.resolve(res["Font"]).(pdfDict); ok {
+			for name, ref := range fontDict {
+				if fd, ok := f.resolve(ref).(pdfDict); ok {
+					fonts[name] = f.loadFont(fd)
+				}
+			}
+		}
+	}
+	return runContent(content, fonts)
+}
+
+// decode applies the filters of a stream
+func (f *pdfFile) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch filter := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{filter}
+	case pdfArray:
+		filters = filter
+	}
+
+	data := s.data
+	for _, filter := range filters {
+		var err error
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = inflate(data)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data, err = hex.DecodeString(strings.TrimSuffix(string(bytes.Join(bytes.Fields(data), nil)), ">"))
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+			data = bytes.TrimSuffix(data, []byte("~>"))
+			out := make([]byte, len(data)*4/5+4)
+			var n int
+			n, _, err = ascii85.Decode(out, data, true)
+			data = out[:n]
+		default:
+			err = fmt.Errorf("pdf: unsupported filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, tolerating truncated streams and raw
+// deflate, up to maxStreamSize bytes
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamSize+1))
+	if len(out) > maxStreamSize {
+		return nil, fmt.Errorf("pdf: stream inflates to more than %d bytes", maxStreamSize)
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+	return out, err
+}
+
+// pdfFont maps character codes of a font to text
+type pdfFont struct {
+	twoByte   bool
+	toUnicode map[int]string
+}
+
+// loadFont reads the code width and the /ToUnicode CMap of a font
+func (f *pdfFile) loadFont(fd pdfDict) *pdfFont {
+	font := &pdfFont{twoByte: fd["Subtype"] == pdfName("Type0")}
+	if cmap, ok := f.resolve(fd["ToUnicode"]).(*pdfStream); ok {
+		if data, err := f.decode(cmap); err == nil {
+			font.toUnicode = parseCMap(data)
+		}
+	}
+	return font
+}
+
+// text converts a shown string to Unicode
+func (font *pdfFont) text(s pdfString) string {
+	if font == nil {
+		return latin1(s)
+	}
+	step := 1
+	if font.twoByte {
+		step = 2
+	}
+	var sb strings.Builder
+	for i := 0; i+step <= len(s); i += step {
+		code := int(s[i])
+		if step == 2 {
+			code = code<<8 | int(s[i+1])
+		}
+		if u, ok := font.toUnicode[code]; ok {
+			sb.WriteString(u)
+		} else if !font.twoByte {
+			sb.WriteString(latin1(s[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+// parseCMap reads the bfchar and bfrange sections of a ToUnicode CMap
+func parseCMap(data []byte) map[int]string {
+	m := make(map[int]string)
+	lex := &pdfLexer{data: data}
+	var operands []any
+	for {
+		obj, err := lex.object()
+		if err != nil {
+			break
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch kw {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[codeOf(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := codeOf(lo), codeOf(hi)
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16BE(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for code := start; code <= end && code-start < 65536; code++ {
+						r := append([]rune{}, base...)
+						r[len(r)-1] += rune(code - start)
+						m[code] = string(r)
+					}
+				case pdfArray:
+					for j, d := range dst {
+						if s, ok := d.(pdfString); ok && start+j <= end {
+							m[start+j] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return m
+}
+
+func codeOf(s pdfString) int {
+	code := 0
+	for _, b := range s {
+		code = code<<8 | int(b)
+	}
+	return code
+}
+
+func utf16BE(s pdfString) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func latin1(s []byte) string {
+	r := make([]rune, len(s))
+	for i, b := range s {
+		r[i] = rune(b)
+	}
+	return string(r)
+}
+
+// runContent interprets a content stream and returns the text it shows,
+// with line breaks where the text position moves to a new line
+func runContent(content []byte, fonts map[string]*pdfFont) string {
+	var sb bytes.Buffer
+	var font *pdfFont
+	var operands []any
+	lastY, haveY := 0.0, false
+
+	last := func() byte {
+		if sb.Len() == 0 {
+			return '\n'
+		}
+		return sb.Bytes()[sb.Len()-1]
+	}
+	newline := func() {
+		for sb.Len() > 0 && last() == ' ' {
+			sb.Truncate(sb.Len() - 1)
+		}
+		if last() != '\n' {
+			sb.WriteByte('\n')
+		}
+	}
+	space := func() {
+		if c := last(); c != ' ' && c != '\n' {
+			sb.WriteByte(' ')
+		}
+	}
+	num := func(i int) float64 {
+		if i < 0 || i >= len(operands) {
+			return 0
+		}
+		v, _ := operands[i].(float64)
+		return v
+	}
+
+	lex := &pdfLexer{data: content}
+	for {
+		obj, err := lex.object()
+		if err != nil {
+			break
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BI":
+			lex.skipInlineImage()
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = fonts[string(name)]
+				}
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					sb.WriteString(font.text(s))
+				}
+			}
+		case "'", "\"":
+			newline()
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					sb.WriteString(font.text(s))
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				arr, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range arr {
+					switch v := item.(type) {
+					case pdfString:
+						sb.WriteString(font.text(v))
+					case float64:
+						// Large negative kerning is how many PDFs encode spaces
+						if v < -200 {
+							space()
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if num(1) != 0 {
+				newline()
+			} else if num(0) > 0 {
+				space()
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			y := num(5)
+			if haveY && y != lastY {
+				newline()
+			} else if haveY {
+				space()
+			}
+			lastY, haveY = y, true
+		case "ET":
+			space()
+		}
+		operands = operands[:0]
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// pdfLexer reads PDF objects from a byte slice
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int // arrays and dictionaries being read, see maxObjectDepth
+}
+
+var errEOF = errors.New("pdf: unexpected end of data")
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// object reads the next object, combining "N G R" into references
+func (l *pdfLexer) object() (any, error) {
+	obj, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := obj.(float64); !ok {
+		return obj, nil
+	}
+
+	// Look ahead for "gen R"
+	save := l.pos
+	gen, err := l.token()
+	if g, ok := gen.(float64); ok && err == nil {
+		if kw, err := l.token(); err == nil && kw == pdfKeyword("R") {
+			return pdfRef{num: int(obj.(float64)), gen: int(g)}, nil
+		}
+	}
+	l.pos = save
+	return obj, nil
+}
+
+// token reads a single object without reference handling
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.dict()
+	case c == '<':
+		return l.hexString(), nil
+	case c == '[':
+		l.pos++
+		return l.array()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		l.pos++
+		if c == '>' && l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+			return pdfKeyword(">>"), nil
+		}
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if word == "" {
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+	if v, err := strconv.ParseFloat(word, 64); err == nil {
+		return v, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if b, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				sb.WriteByte(b[0])
+				l.pos += 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		l.pos++
+	}
+	return pdfName(sb.String())
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for k := 0; k < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; k++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out, _ := hex.DecodeString(string(digits))
+	return out
+}
+
+func (l *pdfLexer) array() (pdfArray, error) {
+	if err := l.nest(); err != nil {
+		return nil, err
+	}
+	defer l.unnest()
+	var arr pdfArray
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return arr, err
+		}
+		if obj == pdfKeyword("]") {
+			return arr, nil
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (l *pdfLexer) dict() (pdfDict, error) {
+	if err := l.nest(); err != nil {
+		return nil, err
+	}
+	defer l.unnest()
+	d := pdfDict{}
+	for {
+		key, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		if key == pdfKeyword(">>") {
+			return d, nil
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue
+		}
+		val, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		d[string(name)] = val
+	}
+}
+
+// nest enters an array or dictionary, failing past maxObjectDepth
+func (l *pdfLexer) nest() error {
+	if l.depth >= maxObjectDepth {
+		return errObjectDepth
+	}
+	l.depth++
+	return nil
+}
+
+// unnest leaves an array or dictionary entered by nest
+func (l *pdfLexer) unnest() {
+	l.depth--
+}
+
+// stream reads the stream data following dict if the next keyword is "stream"
+func (l *pdfLexer) stream(dict pdfDict, f *pdfFile) (*pdfStream, bool) {
+	save := l.pos
+	if kw, err := l.token(); err != nil || kw != pdfKeyword("stream") {
+		l.pos = save
+		return nil, false
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	// /Length may be an indirect reference to an object not loaded yet,
+	// so fall back to searching for the end marker
+	end := -1
+	if length, ok := f.resolve(dict["Length"]).(float64); ok && length >= 0 && length <= float64(len(l.data)-start) {
+		end = start + int(length)
+		if !bytes.Contains(l.data[end:min(end+32, len(l.data))], []byte("endstream")) {
+			end = -1
+		}
+	}
+	if end < 0 {
+		idx := bytes.Index(l.data[start:], []byte("endstream"))
+		if idx < 0 {
+			return nil, false
+		}
+		end = start + idx
+		for end > start && (l.data[end-1] == '\n' || l.data[end-1] == '\r') {
+			end--
+		}
+	}
+	l.pos = end
+	return &pdfStream{dict: dict, data: l.data[start:end]}, true
+}
+
+// skipInlineImage moves past the binary data of an inline image
+func (l *pdfLexer) skipInlineImage() {
+	idx := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if idx < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += idx + 2
+	for l.pos < len(l.data) {
+		idx := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if idx < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		l.pos += idx + 2
+		before := l.data[l.pos-3]
+		if isPDFSpace(before) && (l.pos >= len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a minimal PDF with the given objects, numbered from 1
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	toUnicode := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0001> <0048> endbfchar
+1 beginbfrange <0002> <0003> <0069> endbfrange
+endcmap`
+
+	pdf := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R 9 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /ToUnicode 10 0 R >>",
+		stream("", []byte("BT /F1 12 Tf 72 700 Td (Hello PDF world.) Tj 0 -14 Td (Second \\(line\\) here.) Tj ET")),
+		stream("/Filter /FlateDecode", deflate("BT /F1 12 Tf 72 700 Td [(Page)-300(tw)20(o.)] TJ ET")),
+		stream("", []byte("BT /F2 12 Tf 72 680 Td <00010002> Tj ET")),
+		stream("", []byte(toUnicode)),
+	)
+
+	result, err := Extract("doc.pdf", pdf)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if result.Format != FormatPDF {
+		t.Errorf("Expected format %q, got %q", FormatPDF, result.Format)
+	}
+
+	want := []Block{
+		{Text: "Hello PDF world.\nSecond (line) here.", Page: 1},
+		{Text: "Page two.\nHi", Page: 2},
+	}
+	if len(result.Blocks) != len(want) {
+		t.Fatalf("Expected %d pages, got %+v", len(want), result.Blocks)
+	}
+	for i := range want {
+		if result.Blocks[i] != want[i] {
+			t.Errorf("Page %d: expected %+v, got %+v", i+1, want[i], result.Blocks[i])
+		}
+	}
+}
+
+func TestExtractPDFWithoutCatalog(t *testing.T) {
+	if _, err := Extract("broken.pdf", []byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\n")); err == nil {
+		t.Errorf("Expected error for PDF without catalog")
+	}
+}
+
+func TestExtractPDFMalformed(t *testing.T) {
+	catalog := "<< /Type /Catalog /Pages 2 0 R >>"
+	pages := "<< /Type /Pages /Kids [3 0 R] >>"
+	page := "<< /Type /Page /Contents 4 0 R >>"
+	// Short enough for "endstream" to follow within the bytes a negative
+	// /Length points at
+	content := "BT (Short.) Tj ET"
+	objStm := func(dict, data string) string {
+		return stream("/Type /ObjStm /N 1 "+dict, []byte(data))
+	}
+
+	tests := []struct {
+		name string
+		pdf  []byte
+	}{
+		{"negative length", buildPDF(catalog, pages, page, "<< /Length -5 >>\nstream\n"+content+"\nendstream")},
+		{"huge length", buildPDF(catalog, pages, page, "<< /Length 1e300 >>\nstream\n"+content+"\nendstream")},
+		{"negative first", buildPDF(catalog, pages, page, stream("", []byte(content)), objStm("/First -1", "9 0 << >>"))},
+		{"negative offset", buildPDF(catalog, pages, page, stream("", []byte(content)), objStm("/First 6", "9 -45 << >>"))},
+		{"offset beyond data", buildPDF(catalog, pages, page, stream("", []byte(content)), objStm("/First 6", "9 500 << >>"))},
+		{"cyclic page tree", buildPDF(catalog, "<< /Type /Pages /Kids [2 0 R 2 0 R 3 0 R 3 0 R] >>", page, stream("", []byte(content)))},
+		{"nested arrays", buildPDF(catalog, pages, page, stream("", []byte(content)), strings.Repeat("[", 4<<20))},
+		{"nested dictionaries", buildPDF(catalog, pages, page, stream("", []byte(content)), strings.Repeat("<< /A ", 1<<20))},
+		{"nested content", buildPDF(catalog, pages, page, stream("", []byte(content+strings.Repeat(" [", 1<<20))))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Extract("broken.pdf", tt.pdf)
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if len(result.Blocks) != 1 || result.Blocks[0].Text != "Short." {
+				t.Errorf("Expected the single page, got %+v", result.Blocks)
+			}
+		})
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	chunk := make([]byte, 1<<20)
+	for range maxStreamSize/len(chunk) + 1 {
+		w.Write(chunk)
+	}
+	w.Close()
+
+	if _, err := inflate(buf.Bytes()); err == nil {
+		t.Errorf("Expected an error for a stream inflating past %d bytes", maxStreamSize)
+	}
+	if out, err := inflate(deflate("BT (Short.) Tj ET")); err != nil || string(out) != "BT (Short.) Tj ET" {
+		t.Errorf("Got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,211 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxRecordLine caps the length of a single NDJSON line
+const maxRecordLine = 16 << 20
+
+// isJSON reports whether data is a JSON array or object
+func isJSON(head, data []byte) bool {
+	head = bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+	if len(head) == 0 || (head[0] != '[' && head[0] != '{') {
+		return false
+	}
+	return json.Valid(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+}
+
+// extractCSV returns one block per non-empty cell, using the header row as
+// field names
+func extractCSV(data []byte, comma rune, format string) (*Result, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return &Result{Format: format}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	fields := csvFieldNames(header)
+
+	result := &Result{Format: format}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]any, len(fields))
+		n := len(result.Records) + 1
+		for i, value := range row {
+			if i >= len(fields) {
+				fields = append(fields, "column "+strconv.Itoa(i+1))
+			}
+			record[fields[i]] = value
+			if text := strings.TrimSpace(value); text != "" {
+				result.Blocks = append(result.Blocks, Block{Text: text, Record: n, Field: fields[i]})
+			}
+		}
+		result.Records = append(result.Records, record)
+	}
+	return result, nil
+}
+
+// csvFieldNames names empty and duplicate header cells by their column
+func csvFieldNames(header []string) []string {
+	fields := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			name = "column " + strconv.Itoa(i+1)
+		}
+		seen[name] = true
+		fields[i] = name
+	}
+	return fields
+}
+
+// extractJSON returns one record per object of a top-level array. An object
+// holding a single array of objects, as many exports do, is unwrapped and
+// any other object is a single record.
+func extractJSON(data []byte) (*Result, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, err
+		}
+		items = []json.RawMessage{raw}
+		if len(object) == 1 {
+			for _, v := range object {
+				var inner []json.RawMessage
+				if json.Unmarshal(v, &inner) == nil && len(inner) > 0 && isJSONObject(inner[0]) {
+					items = inner
+				}
+			}
+		}
+	}
+
+	result := &Result{Format: FormatJSON}
+	for i, item := range items {
+		if err := appendJSONRecord(result, item); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+	}
+	return result, nil
+}
+
+// extractNDJSON returns one record per line holding a JSON object
+func extractNDJSON(data []byte) (*Result, error) {
+	result := &Result{Format: FormatNDJSON}
+	sc := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	sc.Buffer(nil, maxRecordLine)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if err := appendJSONRecord(result, text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// appendJSONRecord adds an object as a record, with one block per string or
+// number in it. Nested fields are named by their path, e.g. "brand.name".
+func appendJSONRecord(result *Result, item []byte) error {
+	if !isJSONObject(item) {
+		return errors.New("expected a JSON object")
+	}
+	dec := json.NewDecoder(bytes.NewReader(item))
+	dec.UseNumber()
+	var record map[string]any
+	if err := dec.Decode(&record); err != nil {
+		return err
+	}
+
+	n := len(result.Records) + 1
+	dec = json.NewDecoder(bytes.NewReader(item))
+	dec.UseNumber()
+	err := walkJSON(dec, "", func(field, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			result.Blocks = append(result.Blocks, Block{Text: value, Record: n, Field: field})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	result.Records = append(result.Records, record)
+	return nil
+}
+
+// walkJSON calls emit for every string and number of the next value in
+// document order. Elements of arrays share the field name of the array.
+func walkJSON(dec *json.Decoder, field string, emit func(field, value string)) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				name := key.(string)
+				if field != "" {
+					name = field + "." + name
+				}
+				if err := walkJSON(dec, name, emit); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for dec.More() {
+				if err := walkJSON(dec, field, emit); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = dec.Token() // closing delimiter
+		return err
+	case string:
+		emit(field, t)
+	case json.Number:
+		emit(field, t.String())
+	}
+	return nil
+}
+
+func isJSONObject(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
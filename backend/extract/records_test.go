@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtractRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		input    string
+		format   string
+		blocks   []Block
+		records  []map[string]any
+	}{
+		{
+			name:     "CSV with quoted values",
+			filename: "products.csv",
+			input:    "name,description,price\nLamp,\"Warm, dimmable light\",19.90\nChair,,49\n",
+			format:   FormatCSV,
+			blocks: []Block{
+				{Text: "Lamp", Record: 1, Field: "name"},
+				{Text: "Warm, dimmable light", Record: 1, Field: "description"},
+				{Text: "19.90", Record: 1, Field: "price"},
+				{Text: "Chair", Record: 2, Field: "name"},
+				{Text: "49", Record: 2, Field: "price"},
+			},
+			records: []map[string]any{
+				{"name": "Lamp", "description": "Warm, dimmable light", "price": "19.90"},
+				{"name": "Chair", "description": "", "price": "49"},
+			},
+		},
+		{
+			name:     "TSV with missing header",
+			filename: "items.tsv",
+			input:    "id\t\nA1\tRed bike\textra\n",
+			format:   FormatTSV,
+			blocks: []Block{
+				{Text: "A1", Record: 1, Field: "id"},
+				{Text: "Red bike", Record: 1, Field: "column 2"},
+				{Text: "extra", Record: 1, Field: "column 3"},
+			},
+			records: []map[string]any{
+				{"id": "A1", "column 2": "Red bike", "column 3": "extra"},
+			},
+		},
+		{
+			name:     "JSON export wrapping an array",
+			filename: "export",
+			input:    `{"products": [{"title": "Desk", "brand": {"name": "Oak & Co"}, "tags": ["wood", "office"], "stock": 3, "sale": true}]}`,
+			format:   FormatJSON,
+			blocks: []Block{
+				{Text: "Desk", Record: 1, Field: "title"},
+				{Text: "Oak & Co", Record: 1, Field: "brand.name"},
+				{Text: "wood", Record: 1, Field: "tags"},
+				{Text: "office", Record: 1, Field: "tags"},
+				{Text: "3", Record: 1, Field: "stock"},
+			},
+			records: []map[string]any{
+				{"title": "Desk", "brand": map[string]any{"name": "Oak & Co"}, "tags": []any{"wood", "office"}, "stock": json.Number("3"), "sale": true},
+			},
+		},
+		{
+			name:     "NDJSON",
+			filename: "log.ndjson",
+			input:    "{\"msg\": \"disk full\"}\n\n{\"msg\": \"restarted\", \"code\": null}\n",
+			format:   FormatNDJSON,
+			blocks: []Block{
+				{Text: "disk full", Record: 1, Field: "msg"},
+				{Text: "restarted", Record: 2, Field: "msg"},
+			},
+			records: []map[string]any{
+				{"msg": "disk full"},
+				{"msg": "restarted", "code": nil},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Extract(tt.filename, []byte(tt.input))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if result.Format != tt.format {
+				t.Errorf("Expected format %q, got %q", tt.format, result.Format)
+			}
+			if !reflect.DeepEqual(result.Blocks, tt.blocks) {
+				t.Errorf("Test %q failed. Got: %+v", tt.name, result.Blocks)
+			}
+			if !reflect.DeepEqual(result.Records, tt.records) {
+				t.Errorf("Test %q failed. Got records: %+v", tt.name, result.Records)
+			}
+		})
+	}
+}
+
+func TestExtractNDJSONInvalidLine(t *testing.T) {
+	if _, err := Extract("bad.jsonl", []byte("{\"a\": 1}\n[1, 2]\n")); err == nil {
+		t.Errorf("Expected error for NDJSON line that is not an object")
+	}
+}
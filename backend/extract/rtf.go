@@ -0,0 +1,194 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/swanckel93/fuzzy_api/charset"
+)
+
+// rtfSkipDestinations are groups whose content is not document text
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "listtable": true, "listoverridetable": true,
+	"rsidtbl": true, "generator": true, "themedata": true, "colorschememapping": true,
+	"latentstyles": true, "datastore": true, "xmlnstbl": true, "fldinst": true,
+	"header": true, "footer": true, "headerl": true, "headerr": true, "footerl": true,
+	"footerr": true, "filetbl": true, "revtbl": true, "mmathPr": true, "pgdsctbl": true,
+}
+
+// rtfSymbols are control words that stand for a character
+var rtfSymbols = map[string]string{
+	"line": "\n", "tab": "\t", "emdash": "—", "endash": "–", "bullet": "•",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	"emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+// rtfState is the formatting state of an RTF group
+type rtfState struct {
+	skip bool
+	uc   int // characters to skip after \uN
+}
+
+// extractRTF returns one block per paragraph, using \outlinelevelN for headings
+func extractRTF(data []byte) (*Result, error) {
+	result := &Result{Format: FormatRTF}
+	var text strings.Builder
+	level := 0
+	state := rtfState{uc: 1}
+	var stack []rtfState
+	skipChars := 0
+	var high rune // high surrogate of a \uN pair waiting for the low one
+
+	// flushHigh writes a high surrogate that was not followed by a low one
+	flushHigh := func() {
+		if high != 0 {
+			text.WriteRune(utf8.RuneError)
+			high = 0
+		}
+	}
+	endParagraph := func() {
+		flushHigh()
+		result.Blocks = appendParagraph(result.Blocks, text.String(), level)
+		text.Reset()
+	}
+	write := func(s string) {
+		if state.skip {
+			return
+		}
+		if skipChars > 0 {
+			skipChars--
+			return
+		}
+		flushHigh()
+		text.WriteString(s)
+	}
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch c {
+		case '{':
+			stack = append(stack, state)
+			i++
+		case '}':
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			i++
+		case '\r', '\n':
+			i++
+		case '\\':
+			word, param, hasParam, next := rtfControl(data, i)
+			i = next
+			switch {
+			case word == "*":
+				state.skip = true
+			case word == "'":
+				write(charset.DecodeWindows1252([]byte{byte(param)}))
+			case word == "u" && hasParam:
+				if param < 0 {
+					param += 65536
+				}
+				// The fallback of a previous \uN ends here
+				skipChars = 0
+				r := rune(param)
+				switch {
+				case r >= 0xD800 && r < 0xDC00:
+					if !state.skip {
+						flushHigh()
+						high = r
+					}
+				case high != 0 && utf16.IsSurrogate(r):
+					r, high = utf16.DecodeRune(high, r), 0
+					write(string(r))
+				default:
+					write(string(r))
+				}
+				skipChars = state.uc
+				continue
+			case word == "uc" && hasParam:
+				state.uc = param
+			case word == "par" || word == "sect" || word == "page":
+				if !state.skip {
+					endParagraph()
+					level = 0
+				}
+			case word == "pard":
+				level = 0
+			case word == "outlinelevel" && hasParam:
+				level = param + 1
+			case word == "{" || word == "}" || word == "\\":
+				write(word)
+			case word == "~":
+				write(" ")
+			case word == "_":
+				write("-")
+			case rtfSkipDestinations[word]:
+				state.skip = true
+			default:
+				if s, ok := rtfSymbols[word]; ok {
+					write(s)
+				}
+			}
+			// Fallback characters of \uN may be hex escapes, any other
+			// control word ends the fallback
+			if word != "'" {
+				skipChars = 0
+			}
+		default:
+			write(charset.DecodeWindows1252([]byte{c}))
+			i++
+		}
+	}
+	endParagraph()
+	assignHeadings(result.Blocks)
+	return result, nil
+}
+
+// rtfControl parses the control word or symbol starting at the backslash at i
+func rtfControl(data []byte, i int) (word string, param int, hasParam bool, next int) {
+	i++
+	if i >= len(data) {
+		return "", 0, false, i
+	}
+	c := data[i]
+	if !isASCIILetter(c) {
+		if c == '\'' && i+2 < len(data) {
+			v, err := strconv.ParseUint(string(data[i+1:i+3]), 16, 8)
+			if err == nil {
+				return "'", int(v), true, i + 3
+			}
+		}
+		return string(c), 0, false, i + 1
+	}
+
+	start := i
+	for i < len(data) && isASCIILetter(data[i]) {
+		i++
+	}
+	word = string(data[start:i])
+
+	numStart := i
+	if i < len(data) && data[i] == '-' {
+		i++
+	}
+	for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+		i++
+	}
+	if i > numStart {
+		param, _ = strconv.Atoi(string(data[numStart:i]))
+		hasParam = true
+	}
+	// A single space delimits the control word and is not text
+	if i < len(data) && data[i] == ' ' {
+		i++
+	}
+	return word, param, hasParam, i
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -1,38 +1,76 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
-	// "fmt"
+	"errors"
+	"fmt"
+	"github.com/swanckel93/fuzzy_api/analysis"
+	"github.com/swanckel93/fuzzy_api/archive"
+	"github.com/swanckel93/fuzzy_api/autocomplete"
+	"github.com/swanckel93/fuzzy_api/ingest"
+	"github.com/swanckel93/fuzzy_api/jobs"
 	"github.com/swanckel93/fuzzy_api/models"
 	"github.com/swanckel93/fuzzy_api/search"
 	"github.com/swanckel93/fuzzy_api/searchCache"
 	"github.com/swanckel93/fuzzy_api/storage"
+	"github.com/swanckel93/fuzzy_api/synonyms"
 	"github.com/swanckel93/fuzzy_api/utils"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 	"time"
 	"log"
 )
 
+// MaxUploadSize caps the request body of an upload, in bytes
+var MaxUploadSize int64 = 512 << 20
+
+// maxFormValueSize caps upload form fields other than the file
+const maxFormValueSize = 64 << 10
+
 // enableCors sets headers for CORS, including preflight support
 func enableCors(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
 	}
 }
 // UploadHandler godoc
-// @Summary Upload a text file
-// @Description Uploads a file, splits it into sentences, and stores it for search
+// @Summary Upload a document
+// @Description Uploads a text, PDF, DOCX, ODT, RTF, EPUB, HTML, Markdown, CSV, TSV, JSON or NDJSON file, splits it into sentences (or another chunking unit), and stores it for search.
+// @Description Zip, tar and tar.gz archives are unpacked and every file is indexed as "<archive>/<path>"; the response is then a JSON report per file. Other gzip-compressed files are decompressed and indexed under their upload name.
+// @Description Plain text is indexed while it is streamed. Form fields must precede the file, or be passed as query parameters.
 // @Tags upload
 // @Accept multipart/form-data
-// @Produce plain
-// @Param file formData file true "Text file to upload"
+// @Produce plain,json
+// @Param file formData file true "Document to upload"
+// @Param analyzer formData string false "Text analysis for token-level matching: none (default), standard, english, german, auto (by detected language)"
+// @Param detect_sentences formData bool false "Also detect the language of every sentence"
+// @Param chunking formData string false "Search unit: sentence (default), line, paragraph or window"
+// @Param window_size formData int false "Characters per window for window chunking (default 200)"
+// @Param window_overlap formData int false "Characters shared by consecutive windows (default 50)"
+// @Param encoding formData string false "Character encoding of text files: utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1 (detected by default)"
+// @Param fields formData string false "Comma-separated record fields to index for CSV, TSV, JSON and NDJSON files (default all)"
+// @Param preprocess formData string false "Comma-separated preprocessing stages run in order: normalize, strip_control, dehyphenate, boilerplate, collapse_whitespace, redact, or none (default set by the server)"
+// @Param async formData bool false "Respond with a job right away and ingest in the background, see /jobs/{id}"
 // @Success 200 {string} string "File uploaded successfully"
-// @Failure 400 {string} string "Unable to parse form or retrieve file"
+// @Success 200 {object} handler.ArchiveReport "Report for archive uploads"
+// @Success 202 {object} jobs.Status "Job for async uploads"
+// @Failure 400 {string} string "Unable to parse form, retrieve file or invalid options"
+// @Failure 413 {string} string "Upload exceeds the size limit, or archive exceeds file count or size limits"
+// @Failure 422 {string} string "Unable to extract text or archive"
 // @Failure 500 {string} string "Error reading file"
+// @Failure 503 {string} string "Job queue is full"
 // @Router /upload [post]
 func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	enableCors(w, r)
@@ -40,32 +78,297 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	mr, err := r.MultipartReader()
+	if err != nil {
 		http.Error(w, "Unable to parse form", http.StatusBadRequest)
 		return
 	}
 
-	file, handler, err := r.FormFile("file")
+	// The file is ingested while it is read, so only form fields sent
+	// before it apply. Options can also be passed as query parameters.
+	form := r.URL.Query()
+	var part *multipart.Part
+	for {
+		part, err = mr.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Error retrieving file", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			uploadError(w, err)
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize))
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		form.Set(part.FormName(), string(value))
+	}
+	defer part.Close()
+	filename := part.FileName()
+
+	opts := ingest.Options{
+		Analyzer: form.Get("analyzer"),
+		Chunking: utils.ChunkOptions{Strategy: form.Get("chunking")},
+	}
+	opts.DetectSentences, _ = strconv.ParseBool(form.Get("detect_sentences"))
+	if opts.Chunking.WindowSize, err = intOption(form, "window_size"); err != nil {
+		uploadError(w, err)
+		return
+	}
+	if opts.Chunking.WindowOverlap, err = intOption(form, "window_overlap"); err != nil {
+		uploadError(w, err)
+		return
+	}
+	opts.Encoding = form.Get("encoding")
+	opts.Preprocess = form.Get("preprocess")
+	for _, f := range strings.Split(form.Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			opts.Fields = append(opts.Fields, f)
+		}
+	}
+
+	if async, _ := strconv.ParseBool(form.Get("async")); async {
+		submitUpload(w, filename, part, opts)
+		return
+	}
+
+	report, err := ingestUpload(r.Context(), filename, part, opts)
 	if err != nil {
-		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		uploadError(w, err)
 		return
 	}
-	defer file.Close()
+	if report != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("File uploaded successfully"))
+}
 
-	content, err := io.ReadAll(file)
+// submitUpload stores the uploaded file in a temporary file and ingests it
+// in a background job, responding with the job
+func submitUpload(w http.ResponseWriter, filename string, file io.Reader, opts ingest.Options) {
+	if err := opts.Validate(); err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	tmp, err := os.CreateTemp("", "upload-*")
 	if err != nil {
 		http.Error(w, "Error reading file", http.StatusInternalServerError)
 		return
 	}
+	size, err := io.Copy(tmp, file)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		uploadError(w, err)
+		return
+	}
 
-	text := string(content)
-	sentences := utils.SplitIntoSentences(text)
+	status, err := jobs.Submit(filename, size, func(ctx context.Context, j *jobs.Job) (any, error) {
+		defer os.Remove(tmp.Name())
+		f, err := os.Open(tmp.Name())
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
 
-	filename := handler.Filename
-	storage.AddFile(filename, sentences)
+		opts.Progress = j.SetSentences
+		report, err := ingestUpload(ctx, filename, j.Reader(f), opts)
+		if report == nil {
+			return nil, err
+		}
+		return report, err
+	})
+	if err != nil {
+		os.Remove(tmp.Name())
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("File uploaded successfully"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+status.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// ingestUpload builds and stores the documents of an uploaded file. For
+// archives it returns a report of every file in it. Nothing is stored once
+// ctx is done.
+func ingestUpload(ctx context.Context, filename string, file io.Reader, opts ingest.Options) (*ArchiveReport, error) {
+	br := bufio.NewReaderSize(file, ingest.SniffSize)
+	var doc *storage.Document
+	var err error
+	head, _ := br.Peek(ingest.SniffSize)
+	switch format := archive.Format(filename, head); {
+	case format == archive.FormatTar || format == archive.FormatTarGz:
+		return ingestArchive(ctx, filename, br, format, opts)
+	case format == archive.FormatZip:
+		// Zip archives need random access, and zip based documents such
+		// as DOCX are only told apart from them by their whole content
+		var content []byte
+		if content, err = io.ReadAll(br); err != nil {
+			return nil, err
+		}
+		if format := archive.Format(filename, content); format != "" {
+			return ingestArchive(ctx, filename, bytes.NewReader(content), format, opts)
+		}
+		doc, err = ingest.Build(filename, content, opts)
+	case archive.IsGzip(head):
+		// A single compressed file is indexed by its content, under the
+		// name it was uploaded with, as long as it stays within the
+		// upload limit
+		name, gz, err := archive.Gunzip(filename, br, MaxUploadSize)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		if doc, err = ingest.BuildStream(name, gz, opts); err != nil {
+			return nil, err
+		}
+	default:
+		doc, err = ingest.BuildStream(filename, br, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	storage.AddDocument(filename, doc)
+	return nil, nil
+}
+
+// intOption parses a numeric upload option, 0 if it is not set
+func intOption(form url.Values, name string) (int, error) {
+	v := form.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be a number, got %q", ingest.ErrInvalidOptions, name, v)
+	}
+	return n, nil
+}
+
+// uploadError responds to an error that ended an upload
+func uploadError(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		http.Error(w, fmt.Sprintf("File exceeds the upload limit of %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, archive.ErrLimit):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ingest.ErrInvalidOptions):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Unable to extract text: "+err.Error(), http.StatusUnprocessableEntity)
+	}
+}
+
+// ArchiveReport lists the outcome for every file of an uploaded archive
+type ArchiveReport struct {
+	Archive string               `json:"archive"`
+	Indexed int                  `json:"indexed"`
+	Failed  int                  `json:"failed"`
+	Entries []ArchiveEntryReport `json:"entries"`
+}
+
+// ArchiveEntryReport is the outcome for one file of an archive
+type ArchiveEntryReport struct {
+	Name      string `json:"name"`              // path inside the archive
+	FileID    string `json:"file_id,omitempty"` // id to search the file by, "<archive>/<name>"
+	Status    string `json:"status"`            // "indexed" or "failed"
+	Format    string `json:"format,omitempty"`
+	Sentences int    `json:"sentences,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ingestArchive indexes every file of an archive read from r
+func ingestArchive(ctx context.Context, filename string, r io.Reader, format string, opts ingest.Options) (*ArchiveReport, error) {
+	entries, err := ingest.BuildArchive(r, format, opts, archive.DefaultLimits)
+	if err != nil {
+		if errors.Is(err, ingest.ErrInvalidOptions) || errors.Is(err, archive.ErrLimit) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &ArchiveReport{Archive: filename, Entries: make([]ArchiveEntryReport, 0, len(entries))}
+	for _, e := range entries {
+		entry := ArchiveEntryReport{Name: e.Name}
+		if e.Err != nil {
+			entry.Status = "failed"
+			entry.Error = e.Err.Error()
+			report.Failed++
+		} else {
+			entry.FileID = filename + "/" + e.Name
+			entry.Status = "indexed"
+			entry.Format = e.Doc.Meta.Format
+			entry.Sentences = len(e.Doc.Sentences)
+			storage.AddDocument(entry.FileID, e.Doc)
+			report.Indexed++
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	return report, nil
+}
+
+// JobHandler godoc
+// @Summary Get or cancel an upload job
+// @Description GET returns the state, progress and sentence count of an upload started with async=true. DELETE cancels it.
+// @Tags upload
+// @Produce json
+// @Param id path string true "Job ID returned by /upload"
+// @Success 200 {object} jobs.Status
+// @Failure 404 {string} string "Job not found"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 409 {string} string "Job already finished"
+// @Router /jobs/{id} [get]
+// @Router /jobs/{id} [delete]
+func JobHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(w, r)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	id := r.PathValue("id")
+	var status jobs.Status
+	var ok bool
+	switch r.Method {
+	case http.MethodGet:
+		status, ok = jobs.Get(id)
+	case http.MethodDelete:
+		var err error
+		status, ok, err = jobs.Cancel(id)
+		if errors.Is(err, jobs.ErrFinished) {
+			http.Error(w, "Job already finished", http.StatusConflict)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
 }
 
 // ListFilesHandler godoc
@@ -87,14 +390,19 @@ func ListFilesHandler(w http.ResponseWriter, r *http.Request) {
 
 // SearchHandler godoc
 // @Summary Perform a fuzzy search
-// @Description Searches the uploaded file with fuzzy matching and returns matched sentences
+// @Description Searches the uploaded file with fuzzy matching and returns matched sentences.
+// @Description Query terms are expanded with the synonyms of the requested collection first.
+// @Description Documents uploaded with an analyzer are matched term by term on stemmed words.
+// @Description Results from CSV, TSV, JSON and NDJSON files carry the whole record; set field to search a single field.
+// @Description Identical searches running at the same time are computed once.
 // @Tags search
 // @Accept json
 // @Produce json
 // @Param request body models.SearchRequest true "Search input"
 // @Success 200 {array} search.SearchResult
-// @Failure 400 {string} string "Invalid request"
+// @Failure 400 {string} string "Invalid request or unknown field"
 // @Failure 404 {string} string "File not found"
+// @Failure 500 {string} string "Search failed"
 // @Router /search [post]
 func SearchHandler(w http.ResponseWriter, r *http.Request, cache *searchCache.SearchCache) {
 	enableCors(w, r)
@@ -109,34 +417,277 @@ func SearchHandler(w http.ResponseWriter, r *http.Request, cache *searchCache.Se
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
-	// 1. Try cache first
-	cachedResults, found := cache.Get(req.FileID, req.Query)
-	if found {
-		json.NewEncoder(w).Encode(cachedResults)
+	// Expanding is cheap, and keying the cache on the variants keeps
+	// cached results valid when a collection's synonyms change
+	variants := synonyms.ExpandQuery(req.Collection, req.Query)
+	req.Language = strings.ToLower(strings.TrimSpace(req.Language))
+	options := ""
+	if req.Language != "" {
+		options += "\x02" + req.Language
+	}
+	if req.Field != "" {
+		options += "\x03" + req.Field
+	}
+
+	// Identical searches in flight share one computation, cached once
+	results, _, err := cache.Do(req.FileID, variantsKey(variants)+options, func() ([]search.SearchResult, *search.Candidates, error) {
+		return runSearch(req, variants, func(query string) *search.Candidates {
+			return prefixCandidates(cache, req.FileID, query, options)
+		})
+	})
+	switch {
+	case errors.Is(err, errFileNotFound):
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errUnknownField):
+		http.Error(w, "Unknown field", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Search failed", http.StatusInternalServerError)
 		return
 	}
+	json.NewEncoder(w).Encode(results)
+}
+
+var (
+	errFileNotFound = errors.New("file not found")
+	errUnknownField = errors.New("unknown field")
+)
 
-	// 2. Only fetch from storage if necessary
-	sentences, ok := storage.GetFile(req.FileID)
+// maxRefineLookback is how many bytes shorter than a query the cached
+// searches it can be refined from may be
+const maxRefineLookback = 8
+
+// prefixCandidates returns the candidates cached for the longest prefix of
+// query searched with the same options, or nil
+func prefixCandidates(cache *searchCache.SearchCache, fileID, query, options string) *search.Candidates {
+	for n := len(query) - 1; n > 0 && n >= len(query)-maxRefineLookback; n-- {
+		if !utf8.RuneStart(query[n]) {
+			continue
+		}
+		if cand, ok := cache.Candidates(fileID, query[:n]+options); ok {
+			return cand
+		}
+	}
+	return nil
+}
+
+// runSearch searches the requested document for the query variants. Plain
+// fuzzy searches are refined from the candidates prefix returns for the
+// query when possible, and return candidates for longer queries.
+func runSearch(req models.SearchRequest, variants []search.Variant, prefix func(query string) *search.Candidates) ([]search.SearchResult, *search.Candidates, error) {
+	doc, ok := storage.GetDocument(req.FileID)
 	if !ok {
-		http.Error(w, "File not found", http.StatusNotFound)
+		return nil, nil, errFileNotFound
+	}
+
+	var results []search.SearchResult
+	var candidates *search.Candidates
+	sentences, tokens := filterLanguage(doc, req.Language)
+	if req.Field != "" {
+		if !hasField(doc, req.Field) {
+			return nil, nil, errUnknownField
+		}
+		sentences, tokens = filterField(doc, req.Field, sentences, tokens)
+	}
+	if analyzer, ok := analysis.Get(doc.Meta.Analyzer); ok {
+		results = search.TokenSearch(variants, analyzer, sentences, tokens)
+	} else if len(variants) == 1 && variants[0].Expansion == "" {
+		// Search-as-you-type mostly extends the previous query
+		query, refined := variants[0].Query, false
+		if cand := prefix(query); cand != nil {
+			results, candidates, refined = search.RefineSearch(query, cand, sentences)
+		}
+		if !refined {
+			results, candidates = search.FuzzySearchCandidates(query, sentences)
+		}
+	} else {
+		results = search.FuzzySearchVariants(variants, sentences)
+	}
+	decorate(doc, results)
+	return results, candidates, nil
+}
+
+// decorate sets where in the original file the results were found
+func decorate(doc *storage.Document, results []search.SearchResult) {
+	for i := range results {
+		if results[i].SentenceIndex < len(doc.Sources) {
+			results[i].Source = doc.Sources[results[i].SentenceIndex]
+		}
+		if rec := results[i].Record; rec > 0 && rec <= len(doc.Records) {
+			results[i].Values = doc.Records[rec-1]
+		}
+	}
+}
+
+// ResolveResults fills in the sentences, sources and record values of
+// results a compact cache stored as sentence indices, see
+// searchCache.SetCompact
+func ResolveResults(fileID string, results []search.SearchResult) bool {
+	doc, ok := storage.GetDocument(fileID)
+	if !ok {
+		return false
+	}
+	for i := range results {
+		if results[i].SentenceIndex >= len(doc.Sentences) {
+			return false
+		}
+		results[i].Sentence = doc.Sentences[results[i].SentenceIndex]
+	}
+	decorate(doc, results)
+	return true
+}
+
+// filterLanguage blanks out the sentences (and their tokens) that are not in
+// lang, so they cannot match while sentence indices stay intact. Without
+// per-sentence languages the document language decides for all sentences.
+// Languages are compared case-insensitively.
+func filterLanguage(doc *storage.Document, lang string) ([]string, [][]analysis.Token) {
+	lang = strings.ToLower(lang)
+	if lang == "" {
+		return doc.Sentences, doc.Tokens
+	}
+	if doc.Languages == nil {
+		if doc.Meta.Language == lang {
+			return doc.Sentences, doc.Tokens
+		}
+		return nil, nil
+	}
+
+	sentences := make([]string, len(doc.Sentences))
+	var tokens [][]analysis.Token
+	if doc.Tokens != nil {
+		tokens = make([][]analysis.Token, len(doc.Tokens))
+	}
+	for i, l := range doc.Languages {
+		if l != lang {
+			continue
+		}
+		sentences[i] = doc.Sentences[i]
+		if tokens != nil {
+			tokens[i] = doc.Tokens[i]
+		}
+	}
+	return sentences, tokens
+}
+
+// hasField reports whether field, or a field nested in it, was indexed
+func hasField(doc *storage.Document, field string) bool {
+	for _, f := range doc.Meta.Fields {
+		if models.InField(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterField blanks out the sentences (and their tokens) that were not taken
+// from field or a field nested in it
+func filterField(doc *storage.Document, field string, sentences []string, tokens [][]analysis.Token) ([]string, [][]analysis.Token) {
+	if sentences == nil {
+		return nil, nil
+	}
+	scoped := make([]string, len(sentences))
+	var scopedTokens [][]analysis.Token
+	if tokens != nil {
+		scopedTokens = make([][]analysis.Token, len(tokens))
+	}
+	for i, src := range doc.Sources {
+		if i >= len(scoped) || !models.InField(src.Field, field) {
+			continue
+		}
+		scoped[i] = sentences[i]
+		if scopedTokens != nil {
+			scopedTokens[i] = tokens[i]
+		}
+	}
+	return scoped, scopedTokens
+}
+
+// variantsKey builds the cache key for a set of query variants. A query
+// without expansions is keyed by itself.
+func variantsKey(variants []search.Variant) string {
+	if len(variants) == 1 && variants[0].Expansion == "" {
+		return variants[0].Query
+	}
+	parts := make([]string, len(variants))
+	for i, v := range variants {
+		parts[i] = v.Query + "\x01" + v.Expansion
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// SynonymsHandler godoc
+// @Summary Upload or list synonym collections
+// @Description POST uploads a Solr-style synonyms file for a collection, replacing any previous list.
+// @Description GET returns the names of all collections with synonyms.
+// @Tags synonyms
+// @Accept multipart/form-data
+// @Produce plain
+// @Param file formData file true "Synonyms file"
+// @Param collection formData string false "Collection name (default: \"default\")"
+// @Success 200 {string} string "Synonyms uploaded successfully"
+// @Failure 400 {string} string "Unable to parse form, retrieve file or parse synonyms"
+// @Failure 405 {string} string "Method not allowed"
+// @Router /synonyms [post]
+// @Router /synonyms [get]
+func SynonymsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(w, r)
+	switch r.Method {
+	case http.MethodOptions:
+		return
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(synonyms.ListCollections())
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// 3. Do fuzzy search, cache, respond
-	results := search.FuzzySearch(req.Query, sentences)
-	cache.Set(req.FileID, req.Query, results)
-	json.NewEncoder(w).Encode(results)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Unable to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	set, err := synonyms.Parse(file)
+	if err != nil {
+		http.Error(w, "Invalid synonyms: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collection := r.FormValue("collection")
+	if collection == "" {
+		collection = synonyms.DefaultCollection
+	}
+	synonyms.SetCollection(collection, set)
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Synonyms uploaded successfully"))
+}
+
+// ExpandContextResponse is a single unit of a document with its location
+type ExpandContextResponse struct {
+	Context string `json:"context"`
+	Unit    string `json:"unit"` // chunking unit of the document, e.g. "sentence" or "line"
+	models.Source
 }
 
 // ExpandContextHandler godoc
 // @Summary Expand context for a matched sentence
-// @Description Returns the sentence at the given index from the uploaded file
+// @Description Returns the unit (sentence, line, paragraph or window, depending on how the file was chunked) at the given index
 // @Tags context
 // @Accept json
 // @Produce json
 // @Param request body models.ExpandContextRequest true "Context input"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} handler.ExpandContextResponse
 // @Failure 400 {string} string "Invalid request or index"
 // @Router /expand-context [post]
 func ExpandContextHandler(w http.ResponseWriter, r *http.Request) {
@@ -151,17 +702,130 @@ func ExpandContextHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sentences, ok := storage.GetFile(req.FileID)
-	if !ok || req.Index < 0 || req.Index >= len(sentences) {
+	doc, ok := storage.GetDocument(req.FileID)
+	if !ok || req.Index < 0 || req.Index >= len(doc.Sentences) {
 		http.Error(w, "Invalid index or file", http.StatusBadRequest)
 		return
 	}
 
-	context := sentences[req.Index]
+	resp := ExpandContextResponse{
+		Context: doc.Sentences[req.Index],
+		Unit:    doc.Meta.Chunking,
+	}
+	if req.Index < len(doc.Sources) {
+		resp.Source = doc.Sources[req.Index]
+	}
+	json.NewEncoder(w).Encode(resp)
+}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"context": context,
-	})
+// AutocompleteHandler godoc
+// @Summary Complete a search prefix
+// @Description Returns words and frequent phrases of the uploaded file that complete the prefix, tolerating one typo
+// @Tags search
+// @Produce json
+// @Param file_id query string true "File to complete against"
+// @Param prefix query string true "Typed prefix"
+// @Param limit query int false "Maximum number of completions (default 10, max 20)"
+// @Success 200 {array} autocomplete.Completion
+// @Failure 400 {string} string "Invalid request or limit"
+// @Failure 404 {string} string "File not found"
+// @Router /autocomplete [get]
+func AutocompleteHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(w, r)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	fileID := r.URL.Query().Get("file_id")
+	prefix := r.URL.Query().Get("prefix")
+	if fileID == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > autocomplete.MaxLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	completions, ok := autocomplete.Complete(fileID, prefix, limit)
+	if !ok {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(completions)
+}
+
+// DocumentHandler godoc
+// @Summary Get document metadata
+// @Description Returns the metadata stored for an uploaded file, such as its detected language and analyzer
+// @Tags files
+// @Produce json
+// @Param file_id query string true "File to describe"
+// @Success 200 {object} storage.Metadata
+// @Failure 404 {string} string "File not found"
+// @Router /document [get]
+func DocumentHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(w, r)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	doc, ok := storage.GetDocument(r.URL.Query().Get("file_id"))
+	if !ok {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(doc.Meta)
+}
+
+// CacheFlushReport is the response of DELETE /admin/cache
+type CacheFlushReport struct {
+	FileID  string `json:"file_id,omitempty"` // document whose entries were removed, empty for all
+	Removed int    `json:"removed"`
+}
+
+// CacheHandler godoc
+// @Summary Inspect or flush the search cache
+// @Description GET returns hit, miss, eviction and expiration counts and the bytes in use.
+// @Description DELETE removes all entries, or only those of the document given by file_id.
+// @Tags admin
+// @Produce json
+// @Param file_id query string false "Document whose entries to remove (DELETE only, default all)"
+// @Success 200 {object} searchCache.Stats
+// @Failure 405 {string} string "Method not allowed"
+// @Router /admin/cache [get]
+// @Router /admin/cache [delete]
+func CacheHandler(w http.ResponseWriter, r *http.Request, cache *searchCache.SearchCache) {
+	enableCors(w, r)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	var response any
+	switch r.Method {
+	case http.MethodGet:
+		response = cache.Stats()
+	case http.MethodDelete:
+		report := CacheFlushReport{FileID: r.URL.Query().Get("file_id")}
+		if report.FileID != "" {
+			report.Removed = cache.Invalidate(report.FileID)
+		} else {
+			report.Removed = cache.Clear()
+		}
+		response = report
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 // Logger middleware for logging requests and response status
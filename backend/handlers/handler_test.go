@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/swanckel93/fuzzy_api/archive"
+	"github.com/swanckel93/fuzzy_api/autocomplete"
+	"github.com/swanckel93/fuzzy_api/ingest"
+	"github.com/swanckel93/fuzzy_api/storage"
+)
+
+func TestFilterLanguage(t *testing.T) {
+	sentences := []string{"The invoice is due.", "Die Rechnung ist fällig.", "Paid in full."}
+	perSentence := &storage.Document{Sentences: sentences, Languages: []string{"en", "de", "en"}}
+	perSentence.Meta.Language = "en"
+	whole := &storage.Document{Sentences: sentences}
+	whole.Meta.Language = "en"
+
+	tests := []struct {
+		name string
+		doc  *storage.Document
+		lang string
+		want []string
+	}{
+		{"no filter", perSentence, "", sentences},
+		{"per sentence", perSentence, "de", []string{"", "Die Rechnung ist fällig.", ""}},
+		{"upper case", perSentence, "EN", []string{"The invoice is due.", "", "Paid in full."}},
+		{"document language", whole, "En", sentences},
+		{"other document language", whole, "de", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := filterLanguage(tt.doc, tt.lang)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Test %q failed. Got: %q", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestIntOption(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"120", 120, false},
+		{"abc", 0, true},
+		{"1.5", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := intOption(url.Values{"window_size": {tt.value}}, "window_size")
+		if got != tt.want || (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ingest.ErrInvalidOptions)) {
+			t.Errorf("Test %q failed. Got: %d, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestAutocompleteLimit(t *testing.T) {
+	autocomplete.AddDocument("doc", []string{"The invoice is due."})
+	tests := []struct {
+		limit string
+		want  int
+	}{
+		{"", http.StatusOK},
+		{"20", http.StatusOK},
+		{"21", http.StatusBadRequest},
+		{"0", http.StatusBadRequest},
+		{"abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		AutocompleteHandler(w, httptest.NewRequest(http.MethodGet, "/autocomplete?file_id=doc&prefix=inv&limit="+tt.limit, nil))
+		if w.Code != tt.want {
+			t.Errorf("Test %q failed. Got: %d", tt.limit, w.Code)
+		}
+	}
+}
+
+func TestIngestGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte(strings.Repeat("The invoice is due. ", 50)))
+	gw.Close()
+
+	// Single files are only limited like uploads, not like archive entries
+	defer func(entry, upload int64) {
+		archive.DefaultLimits.MaxEntrySize, MaxUploadSize = entry, upload
+	}(archive.DefaultLimits.MaxEntrySize, MaxUploadSize)
+	archive.DefaultLimits.MaxEntrySize = 100
+	if _, err := ingestUpload(context.Background(), "notes.txt.gz", bytes.NewReader(buf.Bytes()), ingest.Options{}); err != nil {
+		t.Fatalf("Test %q failed. Error: %v", "entry limit", err)
+	}
+	if doc, ok := storage.GetDocument("notes.txt.gz"); !ok || len(doc.Sentences) != 50 {
+		t.Errorf("Test %q failed. Got: %+v", "entry limit", doc)
+	}
+
+	MaxUploadSize = 100
+	if _, err := ingestUpload(context.Background(), "big.txt.gz", bytes.NewReader(buf.Bytes()), ingest.Options{}); !errors.Is(err, archive.ErrLimit) {
+		t.Errorf("Test %q failed. Got: %v", "upload limit", err)
+	}
+}
+
+func TestSubmitUploadInvalidAnalyzer(t *testing.T) {
+	// Options are rejected before the upload is stored for a job
+	w := httptest.NewRecorder()
+	submitUpload(w, "notes.txt", strings.NewReader("Some text."), ingest.Options{Analyzer: "klingon"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Test %q failed. Got: %d %s", "invalid analyzer", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,432 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/swanckel93/fuzzy_api/analysis"
+	"github.com/swanckel93/fuzzy_api/archive"
+	"github.com/swanckel93/fuzzy_api/charset"
+	"github.com/swanckel93/fuzzy_api/extract"
+	"github.com/swanckel93/fuzzy_api/language"
+	"github.com/swanckel93/fuzzy_api/models"
+	"github.com/swanckel93/fuzzy_api/preprocess"
+	"github.com/swanckel93/fuzzy_api/storage"
+	"github.com/swanckel93/fuzzy_api/utils"
+)
+
+// ErrInvalidOptions is wrapped by errors caused by bad upload options
+var ErrInvalidOptions = errors.New("invalid upload options")
+
+// blockSeparator joins extracted blocks into the text that offsets refer to
+const blockSeparator = "\n\n"
+
+// SniffSize is how much of a streamed upload is inspected to detect its
+// format and encoding
+const SniffSize = 8 << 10
+
+// languageSample is how much decoded text of a streamed upload the
+// document language is detected on
+const languageSample = 64 << 10
+
+// Options control how an uploaded file becomes a searchable document
+type Options struct {
+	Chunking        utils.ChunkOptions
+	Analyzer        string   // analyzer name, "auto" to pick by language, "" or "none" for plain fuzzy matching
+	DetectSentences bool     // detect the language of every unit, not only the document
+	Fields          []string // record fields to index for structured formats, all if empty
+	Encoding        string   // encoding of text formats, detected if empty
+	Preprocess      string   // comma-separated preprocessing stages, preprocess.Default if empty, "none" to skip
+
+	Progress func(units int) // called with the number of units produced so far, may be nil
+
+	pipeline preprocess.Pipeline // parsed from Preprocess by Validate
+}
+
+// progressInterval is how many units BuildStream produces between calls
+// of Options.Progress
+const progressInterval = 1000
+
+// reportProgress calls opts.Progress, if set
+func (o *Options) reportProgress(units int) {
+	if o.Progress != nil {
+		o.Progress(units)
+	}
+}
+
+// Validate fills in defaults and checks the options that do not depend on the uploaded content
+func (o *Options) Validate() error {
+	if err := o.Chunking.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+	}
+	if o.Encoding != "" && charset.Normalize(o.Encoding) == "" {
+		return fmt.Errorf("%w: unknown encoding, expected one of: %s",
+			ErrInvalidOptions, strings.Join(charset.Supported(), ", "))
+	}
+	if !validAnalyzer(o.Analyzer) {
+		return fmt.Errorf("%w: unknown analyzer, expected one of: none, auto, %s",
+			ErrInvalidOptions, strings.Join(analysis.Names(), ", "))
+	}
+	o.pipeline = preprocess.Default
+	if o.Preprocess != "" {
+		pipeline, err := preprocess.Parse(o.Preprocess)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+		}
+		o.pipeline = pipeline
+	}
+	return nil
+}
+
+// Build extracts the text of an uploaded file, splits it into units and
+// analyzes them according to opts
+func Build(filename string, data []byte, opts Options) (*storage.Document, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Text formats are transcoded first, so that extraction and
+	// segmentation only ever see UTF-8
+	encoding := ""
+	if extract.IsText(extract.Sniff(filename, data)) {
+		encoding = charset.Normalize(opts.Encoding)
+		if encoding == "" {
+			encoding = charset.Detect(data)
+		}
+		text, err := charset.Decode(data, encoding)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(text)
+	}
+
+	result, err := extract.Extract(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := selectFields(result, opts.Fields); err != nil {
+		return nil, err
+	}
+	result.Blocks = opts.pipeline.Apply(result.Blocks)
+
+	texts := make([]string, len(result.Blocks))
+	for i, b := range result.Blocks {
+		texts[i] = b.Text
+	}
+	lang := language.Detect(strings.Join(texts, blockSeparator))
+	opts.Chunking.Lang = lang
+
+	doc := newDocument(result.Format, encoding, opts)
+	if result.Records != nil {
+		// Record values are shown with results, so they are rewritten
+		// too, e.g. to not reveal redacted text
+		for _, record := range result.Records {
+			preprocessValues(record, opts.pipeline)
+		}
+		doc.Records = result.Records
+		doc.Meta.Records = len(result.Records)
+		doc.Meta.Fields = blockFields(result.Blocks)
+	}
+
+	if err := chunkBlocks(doc, result.Blocks, opts.Chunking); err != nil {
+		return nil, err
+	}
+	opts.reportProgress(len(doc.Sentences))
+	if err := finish(doc, opts); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// BuildStream is Build for content read from r. Plain text is decoded and
+// split into units while it is read, so it is never held in memory as a
+// whole. Other formats need random access or a complete parse and are read
+// into memory first.
+func BuildStream(filename string, r io.Reader, opts Options) (*storage.Document, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, SniffSize)
+	head, err := br.Peek(SniffSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !streamable(filename, head) {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return Build(filename, data, opts)
+	}
+
+	encoding := charset.Normalize(opts.Encoding)
+	if encoding == "" {
+		encoding = charset.Detect(head)
+	}
+	decoded, err := charset.NewReader(br, encoding)
+	if err != nil {
+		return nil, err
+	}
+	dr := bufio.NewReaderSize(decoded, languageSample)
+	sample, err := dr.Peek(languageSample)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	opts.Chunking.Lang = language.Detect(strings.ToValidUTF8(string(sample), ""))
+
+	doc := newDocument(extract.FormatText, encoding, opts)
+	var prepare func(string, bool) string
+	if !opts.pipeline.Empty() {
+		prepare = opts.pipeline.Stream().Apply
+	}
+	err = utils.ChunkStream(dr, opts.Chunking, prepare, func(unit string, src models.Source) {
+		doc.Sentences = append(doc.Sentences, unit)
+		doc.Sources = append(doc.Sources, src)
+		if len(doc.Sentences)%progressInterval == 0 {
+			opts.reportProgress(len(doc.Sentences))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	opts.reportProgress(len(doc.Sentences))
+	if err := finish(doc, opts); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// streamable reports whether content starting with head is plain text that
+// can be chunked while it is read
+func streamable(filename string, head []byte) bool {
+	if extract.Sniff(filename, head) != extract.FormatText || archive.Format(filename, head) != "" {
+		return false
+	}
+	// JSON is only recognized once it is complete
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(head) == 0 || (head[0] != '{' && head[0] != '[')
+}
+
+// newDocument returns an empty document described by its format and opts
+func newDocument(format, encoding string, opts Options) *storage.Document {
+	doc := &storage.Document{}
+	doc.Meta.Format = format
+	doc.Meta.Encoding = encoding
+	doc.Meta.Language = opts.Chunking.Lang
+	doc.Meta.Chunking = opts.Chunking.Strategy
+	doc.Meta.Preprocessing = opts.pipeline.Names()
+	if opts.Chunking.Strategy == utils.ChunkWindow {
+		doc.Meta.WindowSize = opts.Chunking.WindowSize
+		doc.Meta.WindowOverlap = opts.Chunking.WindowOverlap
+	}
+	return doc
+}
+
+// finish detects per unit languages and analyzes the units of doc
+func finish(doc *storage.Document, opts Options) error {
+	if opts.DetectSentences {
+		doc.Languages = make([]string, len(doc.Sentences))
+		for i, s := range doc.Sentences {
+			doc.Languages[i] = language.Detect(s)
+		}
+	}
+	return analyze(doc, opts.Analyzer)
+}
+
+// ArchiveEntry is the document built from one file of an archive, or the
+// reason it could not be built
+type ArchiveEntry struct {
+	Name string
+	Doc  *storage.Document
+	Err  error
+}
+
+// BuildArchive builds a document from every file of an archive read from
+// r with the same options, one file at a time. Only errors that affect the
+// whole archive are returned, including archive.ErrLimit.
+func BuildArchive(r io.Reader, format string, opts Options, limits archive.Limits) ([]ArchiveEntry, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Progress counts the units of all files together
+	var entries []ArchiveEntry
+	total := 0
+	entryOpts := opts
+	entryOpts.Progress = func(units int) { opts.reportProgress(total + units) }
+	err := archive.Walk(r, format, limits, func(name string, r io.Reader) error {
+		entry := ArchiveEntry{Name: name}
+		br := bufio.NewReaderSize(r, SniffSize)
+		head, err := br.Peek(SniffSize)
+		switch {
+		case err != nil && !errors.Is(err, io.EOF):
+			entry.Err = err
+		case archive.Format(name, head) != "":
+			entry.Err = errors.New("nested archives are not extracted")
+		default:
+			entry.Doc, entry.Err = BuildStream(name, br, entryOpts)
+		}
+		if errors.Is(entry.Err, archive.ErrLimit) {
+			return entry.Err
+		}
+		if entry.Doc != nil {
+			total += len(entry.Doc.Sentences)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// chunkBlocks splits every block on its own and appends the units to doc,
+// shifting their locations as if the blocks were joined by blockSeparator
+func chunkBlocks(doc *storage.Document, blocks []extract.Block, opts utils.ChunkOptions) error {
+	offset, line, paragraph := 0, 0, 0
+	for _, b := range blocks {
+		units, sources, err := utils.ChunkText(b.Text, opts)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+		}
+
+		lastParagraph := -1
+		for i := range units {
+			src := sources[i]
+			lastParagraph = src.Paragraph
+			src.Offset += offset
+			src.Line += line
+			src.Paragraph += paragraph
+			src.Page = b.Page
+			src.Heading = b.Heading
+			src.HeadingLevel = b.Level
+			src.Record = b.Record
+			src.Field = b.Field
+			src.Chapter = b.Chapter
+			src.ChapterTitle = b.ChapterTitle
+			doc.Sentences = append(doc.Sentences, units[i])
+			doc.Sources = append(doc.Sources, src)
+		}
+
+		offset += len(b.Text) + len(blockSeparator)
+		line += strings.Count(b.Text, "\n") + strings.Count(blockSeparator, "\n")
+		paragraph += lastParagraph + 1
+	}
+	return nil
+}
+
+// selectFields keeps only the blocks of the given record fields. A field
+// also selects the fields nested in it, so "brand" keeps "brand.name".
+func selectFields(result *extract.Result, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	if result.Records == nil {
+		return fmt.Errorf("%w: fields only apply to CSV, TSV, JSON and NDJSON files", ErrInvalidOptions)
+	}
+
+	known := blockFields(result.Blocks)
+	for _, f := range fields {
+		if !slices.ContainsFunc(known, func(k string) bool { return models.InField(k, f) }) {
+			return fmt.Errorf("%w: unknown field %q, expected one of: %s",
+				ErrInvalidOptions, f, strings.Join(known, ", "))
+		}
+	}
+
+	blocks := result.Blocks[:0]
+	for _, b := range result.Blocks {
+		if slices.ContainsFunc(fields, func(f string) bool { return models.InField(b.Field, f) }) {
+			blocks = append(blocks, b)
+		}
+	}
+	result.Blocks = blocks
+	return nil
+}
+
+// preprocessValues runs the text stages of pipeline on the strings of a
+// record, including nested ones
+func preprocessValues(record map[string]any, pipeline preprocess.Pipeline) {
+	if pipeline.Empty() {
+		return
+	}
+	for k, v := range record {
+		record[k] = preprocessValue(v, pipeline)
+	}
+}
+
+func preprocessValue(v any, pipeline preprocess.Pipeline) any {
+	switch v := v.(type) {
+	case string:
+		return pipeline.ApplyText(v)
+	case map[string]any:
+		preprocessValues(v, pipeline)
+	case []any:
+		for i := range v {
+			v[i] = preprocessValue(v[i], pipeline)
+		}
+	}
+	return v
+}
+
+// blockFields lists the fields of blocks in order of first appearance
+func blockFields(blocks []extract.Block) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, b := range blocks {
+		if b.Field != "" && !seen[b.Field] {
+			seen[b.Field] = true
+			fields = append(fields, b.Field)
+		}
+	}
+	return fields
+}
+
+// validAnalyzer reports whether name is accepted as Options.Analyzer
+func validAnalyzer(name string) bool {
+	if name == "" || name == "none" || name == "auto" {
+		return true
+	}
+	_, ok := analysis.Get(name)
+	return ok
+}
+
+// analyze tokenizes every unit with the named analyzer
+func analyze(doc *storage.Document, name string) error {
+	if name == "auto" {
+		name = analyzerForLanguage(doc.Meta.Language)
+	}
+	if name == "" || name == "none" {
+		return nil
+	}
+
+	analyzer, ok := analysis.Get(name)
+	if !ok {
+		return fmt.Errorf("%w: unknown analyzer, expected one of: none, auto, %s",
+			ErrInvalidOptions, strings.Join(analysis.Names(), ", "))
+	}
+	doc.Meta.Analyzer = analyzer.Name
+	doc.Tokens = make([][]analysis.Token, len(doc.Sentences))
+	for i, s := range doc.Sentences {
+		doc.Tokens[i] = analyzer.Analyze(s)
+	}
+	return nil
+}
+
+// analyzerForLanguage picks the analyzer matching a detected language
+func analyzerForLanguage(lang string) string {
+	switch lang {
+	case "en":
+		return "english"
+	case "de":
+		return "german"
+	}
+	return "standard"
+}
@@ -0,0 +1,128 @@
+package ingest
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/swanckel93/fuzzy_api/archive"
+)
+
+func TestDetectSentences(t *testing.T) {
+	text := "Please send me the invoice for last month as soon as possible.\n\n" +
+		"Bitte schicken Sie mir die Rechnung für den letzten Monat so schnell wie möglich.\n\n" +
+		"The meeting has been moved to Friday because of the holiday."
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"per sentence", Options{DetectSentences: true}, []string{"en", "de", "en"}},
+		{"document only", Options{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Build("mixed.txt", []byte(text), tt.opts)
+			if err != nil {
+				t.Fatalf("Test %q failed. Error: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(doc.Languages, tt.want) {
+				t.Errorf("Test %q failed. Got: %v for %q", tt.name, doc.Languages, doc.Sentences)
+			}
+			if doc.Meta.Chunking != "sentence" {
+				t.Errorf("Test %q failed. Default chunking was not recorded: %q", tt.name, doc.Meta.Chunking)
+			}
+			if doc.Meta.Language != "en" {
+				t.Errorf("Test %q failed. Document language: %q", tt.name, doc.Meta.Language)
+			}
+		})
+	}
+}
+
+func TestBuildArchive(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, data string }{
+		{"a.txt", "First file. Second sentence."},
+		{"nested.zip", "PK\x05\x06" + strings.Repeat("\x00", 18)},
+		{"b.md", "# Notes\n\nThird file."},
+	} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.data))})
+		tw.Write([]byte(f.data))
+	}
+	tw.Close()
+
+	entries, err := BuildArchive(bytes.NewReader(buf.Bytes()), archive.FormatTar, Options{}, archive.DefaultLimits)
+	if err != nil {
+		t.Fatalf("Test %q failed. Error: %v", "archive", err)
+	}
+	if len(entries) != 3 || entries[0].Doc == nil || len(entries[0].Doc.Sentences) != 2 ||
+		entries[1].Err == nil || entries[2].Doc == nil {
+		t.Errorf("Test %q failed. Got: %+v", "archive", entries)
+	}
+
+	limits := archive.DefaultLimits
+	limits.MaxTotalSize = 40
+	if _, err := BuildArchive(bytes.NewReader(buf.Bytes()), archive.FormatTar, Options{}, limits); !errors.Is(err, archive.ErrLimit) {
+		t.Errorf("Test %q failed. Got: %v", "limit", err)
+	}
+}
+
+func TestBuildStreamMatchesBuild(t *testing.T) {
+	// Several batches of paragraphs with whitespace that is collapsed, so
+	// that positions shift differently than in the original text
+	var sb strings.Builder
+	sb.WriteString("  \n\n")
+	for i := 0; sb.Len() < 3<<20/4; i++ {
+		fmt.Fprintf(&sb, "Paragraph %d   starts here.\t It has an inter-\nnational line.  \n  And a second  one.\n", i)
+		sb.WriteString(strings.Repeat(" \n", 1+i%4))
+	}
+	data := []byte(sb.String())
+
+	for _, spec := range []string{"", "none", "normalize,dehyphenate,collapse_whitespace,redact"} {
+		t.Run(spec, func(t *testing.T) {
+			opts := Options{Preprocess: spec}
+			want, err := Build("notes.txt", data, opts)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			got, err := BuildStream("notes.txt", bytes.NewReader(data), opts)
+			if err != nil {
+				t.Fatalf("BuildStream failed: %v", err)
+			}
+			if !reflect.DeepEqual(got.Sentences, want.Sentences) {
+				t.Fatalf("Test %q failed. Got %d units, expected %d", spec, len(got.Sentences), len(want.Sentences))
+			}
+			for i := range want.Sources {
+				if got.Sources[i] != want.Sources[i] {
+					t.Fatalf("Test %q failed. Unit %d: got %+v, expected %+v", spec, i, got.Sources[i], want.Sources[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAnalyzer(t *testing.T) {
+	tests := []struct {
+		analyzer string
+		valid    bool
+	}{
+		{"", true},
+		{"none", true},
+		{"auto", true},
+		{"English", true},
+		{"klingon", false},
+	}
+	for _, tt := range tests {
+		opts := Options{Analyzer: tt.analyzer}
+		if err := opts.Validate(); (err == nil) != tt.valid || (err != nil && !errors.Is(err, ErrInvalidOptions)) {
+			t.Errorf("Test %q failed. Got: %v", tt.analyzer, err)
+		}
+	}
+}
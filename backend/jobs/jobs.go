@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	StateQueued   = "queued"
+	StateRunning  = "running"
+	StateDone     = "done"
+	StateFailed   = "failed"
+	StateCanceled = "canceled"
+)
+
+// Workers is the number of jobs that run at the same time
+const Workers = 2
+
+// queueSize is the number of jobs that can wait for a worker
+const queueSize = 64
+
+// retention is how long finished jobs can still be looked up
+const retention = time.Hour
+
+var (
+	// ErrQueueFull is returned by Submit when too many jobs are waiting
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrFinished is returned when canceling a job that already ended
+	ErrFinished = errors.New("job already finished")
+)
+
+// Status is a snapshot of a job
+type Status struct {
+	ID        string    `json:"id"`
+	File      string    `json:"file"`
+	State     string    `json:"state"`    // queued, running, done, failed or canceled
+	Progress  int       `json:"progress"` // percentage of the upload processed
+	Sentences int       `json:"sentences"`
+	Error     string    `json:"error,omitempty"`
+	Result    any       `json:"result,omitempty"` // outcome reported by the job, e.g. an archive report
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Job is an ingestion running in the background
+type Job struct {
+	mu     sync.Mutex
+	status Status
+	size   int64 // bytes to read, 0 if unknown
+	read   int64
+	ctx    context.Context
+	cancel context.CancelFunc
+	run    func(ctx context.Context, j *Job) (any, error)
+}
+
+// queue holds submitted jobs until a worker is free
+type queue struct {
+	mu      sync.RWMutex
+	jobs    map[string]*Job
+	pending chan *Job
+	start   sync.Once
+}
+
+var defaultQueue = &queue{
+	jobs:    make(map[string]*Job),
+	pending: make(chan *Job, queueSize),
+}
+
+// Submit queues run for the given file, of size bytes. What run returns
+// becomes the Result of the job.
+func Submit(file string, size int64, run func(ctx context.Context, j *Job) (any, error)) (Status, error) {
+	q := defaultQueue
+	q.start.Do(func() {
+		for range Workers {
+			go q.work()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	j := &Job{
+		status: Status{ID: newID(), File: file, State: StateQueued, CreatedAt: now, UpdatedAt: now},
+		size:   size,
+		ctx:    ctx,
+		cancel: cancel,
+		run:    run,
+	}
+
+	// Workers may update the job as soon as it is queued
+	status := j.status
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(now)
+	select {
+	case q.pending <- j:
+	default:
+		cancel()
+		return Status{}, ErrQueueFull
+	}
+	q.jobs[status.ID] = j
+	return status, nil
+}
+
+// Get returns the status of a job
+func Get(id string) (Status, bool) {
+	j, ok := defaultQueue.get(id)
+	if !ok {
+		return Status{}, false
+	}
+	return j.Status(), true
+}
+
+// Cancel stops a queued or running job
+func Cancel(id string) (Status, bool, error) {
+	j, ok := defaultQueue.get(id)
+	if !ok {
+		return Status{}, false, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.status.State {
+	case StateDone, StateFailed, StateCanceled:
+		return j.status, true, ErrFinished
+	}
+	j.cancel()
+	if j.status.State == StateQueued {
+		j.finish(StateCanceled, context.Canceled, nil)
+	}
+	return j.status, true, nil
+}
+
+func (q *queue) get(id string) (*Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	j, ok := q.jobs[id]
+	return j, ok
+}
+
+// prune forgets jobs that finished longer than retention ago
+func (q *queue) prune(now time.Time) {
+	for id, j := range q.jobs {
+		s := j.Status()
+		switch s.State {
+		case StateDone, StateFailed, StateCanceled:
+			if now.Sub(s.UpdatedAt) > retention {
+				delete(q.jobs, id)
+			}
+		}
+	}
+}
+
+func (q *queue) work() {
+	for j := range q.pending {
+		j.mu.Lock()
+		if j.status.State == StateQueued {
+			j.status.State = StateRunning
+			j.status.UpdatedAt = time.Now()
+		}
+		j.mu.Unlock()
+
+		// Canceled jobs still run, so they can release their resources,
+		// but they fail at their first read
+		result, err := j.execute()
+
+		j.mu.Lock()
+		switch {
+		case j.status.State == StateCanceled:
+		case j.ctx.Err() != nil:
+			j.finish(StateCanceled, j.ctx.Err(), nil)
+		case err != nil:
+			j.finish(StateFailed, err, nil)
+		default:
+			j.status.Progress = 100
+			j.finish(StateDone, nil, result)
+		}
+		j.mu.Unlock()
+		j.cancel()
+	}
+}
+
+// execute runs the job, turning a panic into an error so that a bad
+// upload only fails its own job instead of the whole server
+func (j *Job) execute() (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v\n%s", j.status.ID, r, debug.Stack())
+			result, err = nil, fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return j.run(j.ctx, j)
+}
+
+// finish sets the final state, with j.mu held
+func (j *Job) finish(state string, err error, result any) {
+	j.status.State = state
+	j.status.Result = result
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+	j.status.UpdatedAt = time.Now()
+}
+
+// Status returns a snapshot of the job
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// SetSentences reports how many sentences were produced so far
+func (j *Job) SetSentences(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Sentences = n
+	j.status.UpdatedAt = time.Now()
+}
+
+// Reader wraps the job's input so that reading it reports progress, and
+// fails once the job is canceled
+func (j *Job) Reader(r io.Reader) io.Reader {
+	return &progressReader{job: j, r: r}
+}
+
+type progressReader struct {
+	job *Job
+	r   io.Reader
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.job.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+
+	j := p.job
+	j.mu.Lock()
+	j.read += int64(n)
+	if j.size > 0 {
+		// 100 is reserved for finished jobs
+		j.status.Progress = min(int(j.read*100/j.size), 99)
+	}
+	j.status.UpdatedAt = time.Now()
+	j.mu.Unlock()
+	return n, err
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
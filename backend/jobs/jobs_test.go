@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor polls a job until it reaches state
+func waitFor(t *testing.T, id, state string) Status {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if s, ok := Get(id); ok && s.State == state {
+			return s
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	s, _ := Get(id)
+	t.Fatalf("Job %s did not reach %q, got %+v", id, state, s)
+	return s
+}
+
+func TestJobProgress(t *testing.T) {
+	input := strings.Repeat("x", 1000)
+	status, err := Submit("doc.txt", int64(len(input)), func(ctx context.Context, j *Job) (any, error) {
+		data, err := io.ReadAll(j.Reader(strings.NewReader(input)))
+		j.SetSentences(len(data) / 10)
+		return "ok", err
+	})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if status.State != StateQueued || status.File != "doc.txt" {
+		t.Errorf("Unexpected initial status %+v", status)
+	}
+
+	done := waitFor(t, status.ID, StateDone)
+	if done.Progress != 100 || done.Sentences != 100 || done.Result != "ok" {
+		t.Errorf("Unexpected final status %+v", done)
+	}
+	if _, _, err := Cancel(status.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Expected ErrFinished, got %v", err)
+	}
+}
+
+func TestJobFailure(t *testing.T) {
+	status, _ := Submit("bad.pdf", 0, func(ctx context.Context, j *Job) (any, error) {
+		return nil, errors.New("broken file")
+	})
+	failed := waitFor(t, status.ID, StateFailed)
+	if failed.Error != "broken file" {
+		t.Errorf("Expected error message, got %+v", failed)
+	}
+}
+
+func TestJobPanic(t *testing.T) {
+	status, _ := Submit("evil.pdf", 0, func(ctx context.Context, j *Job) (any, error) {
+		var pages []int
+		return pages[3], nil
+	})
+	failed := waitFor(t, status.ID, StateFailed)
+	if !strings.Contains(failed.Error, "index out of range") {
+		t.Errorf("Expected the panic message, got %+v", failed)
+	}
+
+	// The worker survives and runs later jobs
+	next, _ := Submit("good.txt", 0, func(ctx context.Context, j *Job) (any, error) { return nil, nil })
+	waitFor(t, next.ID, StateDone)
+}
+
+func TestJobCancel(t *testing.T) {
+	started := make(chan struct{})
+	status, _ := Submit("big.txt", 1<<20, func(ctx context.Context, j *Job) (any, error) {
+		close(started)
+		// An endless input only ends by cancellation
+		_, err := io.Copy(io.Discard, j.Reader(endless{}))
+		return nil, err
+	})
+	<-started
+
+	if _, ok, err := Cancel(status.ID); !ok || err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	canceled := waitFor(t, status.ID, StateCanceled)
+	if canceled.Error != context.Canceled.Error() {
+		t.Errorf("Expected cancellation error, got %+v", canceled)
+	}
+
+	if _, ok := Get("unknown"); ok {
+		t.Errorf("Expected unknown job not to be found")
+	}
+}
+
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) { return len(p), nil }
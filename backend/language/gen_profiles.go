@@ -0,0 +1,162 @@
+//go:build ignore
+
+// gen_profiles writes the reference profiles in profiles/ from the
+// unigram, bigram and trigram models of lingua-go, which were counted on
+// the Leipzig news corpora of every language. Run it with go generate.
+package main
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	module = "github.com/pemistahl/lingua-go@v1.4.0"
+	// size is the number of ranked n-grams written per language
+	size = 400
+)
+
+var languages = []string{"de", "en", "fr"}
+
+func main() {
+	out, err := exec.Command("go", "mod", "download", "-json", module).Output()
+	if err != nil {
+		log.Fatal("Unable to download ", module, ": ", err)
+	}
+	var mod struct{ Dir string }
+	if err := json.Unmarshal(out, &mod); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, lang := range languages {
+		dir := filepath.Join(mod.Dir, "language-models", lang)
+		grams, err := rank(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		header := fmt.Sprintf("# %d most frequent n-grams, generated by gen_profiles.go from %s\n", len(grams), module)
+		if err := os.WriteFile(filepath.Join("profiles", lang+".txt"), []byte(header+strings.Join(grams, "\n")+"\n"), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// rank returns the size most frequent n-grams of a language. The models
+// hold the probability of an n-gram given its first n-1 characters, so
+// frequencies are the products of the probabilities of its prefixes.
+func rank(dir string) ([]string, error) {
+	freq := make(map[string]float64)
+	for _, name := range []string{"unigrams", "bigrams", "trigrams"} {
+		probs, err := readModel(filepath.Join(dir, name+".pb.bin.zip"))
+		if err != nil {
+			return nil, err
+		}
+		for gram, p := range probs {
+			runes := []rune(gram)
+			if prefix := string(runes[:len(runes)-1]); prefix != "" {
+				p *= freq[prefix]
+			}
+			freq[gram] = p
+		}
+	}
+
+	grams := make([]string, 0, len(freq))
+	for g := range freq {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if freq[grams[i]] != freq[grams[j]] {
+			return freq[grams[i]] > freq[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	return grams[:min(size, len(grams))], nil
+}
+
+// readModel reads the n-gram probabilities of a zipped lingua model, a
+// protocol buffer of sets of n-grams (field 4) that share a probability
+// (field 1) and list their n-grams (field 2)
+func readModel(path string) (map[string]float64, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 {
+		return nil, fmt.Errorf("%s: expected a single model", path)
+	}
+	f, err := zr.File[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	probs := make(map[string]float64)
+	err = fields(data, func(num int, value []byte) error {
+		if num != 4 {
+			return nil
+		}
+		var p float64
+		return fields(value, func(num int, value []byte) error {
+			switch num {
+			case 1:
+				p = math.Float64frombits(binary.LittleEndian.Uint64(value))
+			case 2:
+				probs[string(value)] = p
+			}
+			return nil
+		})
+	})
+	return probs, err
+}
+
+// fields calls f with the number and value of every field of a protocol
+// buffer message, which holds varints, doubles and length-delimited fields
+func fields(data []byte, f func(num int, value []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+		var value []byte
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return fmt.Errorf("invalid varint")
+			}
+			value, data = data[:n], data[n:]
+		case 1:
+			if len(data) < 8 {
+				return fmt.Errorf("truncated double")
+			}
+			value, data = data[:8], data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return fmt.Errorf("truncated field")
+			}
+			value, data = data[n:n+int(l)], data[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", key&7)
+		}
+		if err := f(int(key>>3), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package language
+
+import (
+	"embed"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Unknown is reported when text has too few letters to classify
+const Unknown = "und"
+
+const (
+	// profileSize is the number of ranked n-grams kept per profile
+	profileSize = 400
+	// maxNgram is the longest n-gram counted
+	maxNgram = 3
+	// minLetters is the least amount of letters needed for a guess
+	minLetters = 10
+)
+
+//go:generate go run gen_profiles.go
+
+//go:embed profiles/*.txt
+var profileFS embed.FS
+
+// profile maps an n-gram to its frequency rank
+type profile map[string]int
+
+// profiles holds the reference profile of every supported language
+var profiles = loadProfiles()
+
+// Supported returns the codes of all detectable languages
+func Supported() []string {
+	codes := make([]string, 0, len(profiles))
+	for code := range profiles {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Detect returns the ISO 639-1 code of the dominant language of text, using
+// Cavnar-Trenkle out-of-place distance between n-gram rank profiles
+func Detect(text string) string {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters {
+		return Unknown
+	}
+
+	p := buildProfile(text)
+	best, bestDist := Unknown, -1
+	for _, code := range Supported() {
+		if d := distance(p, profiles[code]); bestDist == -1 || d < bestDist {
+			best, bestDist = code, d
+		}
+	}
+	return best
+}
+
+// distance is the sum of rank differences, with missing n-grams at maximum penalty
+func distance(doc, ref profile) int {
+	total := 0
+	for gram, rank := range doc {
+		if refRank, ok := ref[gram]; ok {
+			total += abs(rank - refRank)
+		} else {
+			total += profileSize
+		}
+	}
+	return total
+}
+
+// buildProfile ranks the most frequent 1..maxNgram-grams of the words in
+// text. N-grams do not span word boundaries, as in the reference profiles.
+func buildProfile(text string) profile {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		runes := []rune(w)
+		for n := 1; n <= maxNgram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				counts[string(runes[i:i+n])]++
+			}
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	p := make(profile, len(grams))
+	for rank, g := range grams {
+		p[g] = rank
+	}
+	return p
+}
+
+// loadProfiles reads the reference profiles, which list n-grams one per
+// line, the most frequent first, see gen_profiles.go
+func loadProfiles() map[string]profile {
+	entries, err := profileFS.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]profile, len(entries))
+	for _, e := range entries {
+		data, err := profileFS.ReadFile("profiles/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		p := make(profile)
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" && !strings.HasPrefix(line, "#") {
+				p[line] = len(p)
+			}
+		}
+		out[strings.TrimSuffix(e.Name(), ".txt")] = p
+	}
+	return out
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,37 @@
+package language
+
+import (
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Please send me the invoice for last month as soon as possible.", "en"},
+		{"The meeting has been moved to Friday because of the holiday.", "en"},
+		{"Bitte schicken Sie mir die Rechnung für den letzten Monat so schnell wie möglich.", "de"},
+		{"Die Besprechung wurde wegen des Feiertags auf Freitag verschoben.", "de"},
+		{"Merci de m'envoyer la facture du mois dernier dès que possible.", "fr"},
+		{"La réunion a été déplacée à vendredi à cause du jour férié.", "fr"},
+		// Short sentences, as detected per sentence
+		{"Paid in full.", "en"},
+		{"Thank you very much.", "en"},
+		{"Your order has shipped.", "en"},
+		{"Vielen Dank für alles.", "de"},
+		{"Wir brauchen mehr Zeit.", "de"},
+		{"Ihre Bestellung wurde versandt.", "de"},
+		{"Je voudrais un café.", "fr"},
+		{"Notre bureau est fermé.", "fr"},
+		{"Votre commande a été expédiée.", "fr"},
+		{"Merci !", Unknown},
+		{"42 !", Unknown},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.expected {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.expected)
+		}
+	}
+}
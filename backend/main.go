@@ -5,27 +5,103 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/swanckel93/fuzzy_api/autocomplete"
 	"github.com/swanckel93/fuzzy_api/handlers"
+	"github.com/swanckel93/fuzzy_api/preprocess"
 	"github.com/swanckel93/fuzzy_api/searchCache"
+	"github.com/swanckel93/fuzzy_api/storage"
 	httpSwagger "github.com/swaggo/http-swagger"
 	_ "github.com/swanckel93/fuzzy_api/docs" // required for generated docs
 )
 
 func main() {
+	if v := os.Getenv("MAX_UPLOAD_SIZE"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatal("Invalid MAX_UPLOAD_SIZE, expected a number of bytes: ", v)
+		}
+		handler.MaxUploadSize = size
+	}
+	if v := os.Getenv("PREPROCESS"); v != "" {
+		pipeline, err := preprocess.Parse(v)
+		if err != nil {
+			log.Fatal("Invalid PREPROCESS: ", err)
+		}
+		preprocess.Default = pipeline
+	}
+
 	mux := http.NewServeMux()
 	cache := searchCache.NewSearchCache(50)
+	if v := os.Getenv("CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			log.Fatal("Invalid CACHE_TTL, expected a duration such as 10m: ", v)
+		}
+		cache.SetTTL(ttl)
+	}
+	if v := os.Getenv("CACHE_POLICY"); v != "" {
+		if err := cache.SetPolicy(v); err != nil {
+			log.Fatal("Invalid CACHE_POLICY: ", err)
+		}
+	}
+	if v := os.Getenv("CACHE_COMPACT"); v != "" {
+		compact, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatal("Invalid CACHE_COMPACT, expected true or false: ", v)
+		}
+		if compact {
+			cache.SetCompact(handler.ResolveResults)
+		}
+	}
+	// Results of a replaced document are stale, unless they were loaded
+	// from a snapshot of the same content
+	storage.OnChange(func(filename string) {
+		cache.Invalidate(filename)
+		if hash, ok := storage.DocumentHash(filename); ok {
+			cache.Restore(filename, hash)
+		}
+	})
+	// Completions are built for the stored version of a document only, the
+	// trie of a replaced one is dropped
+	storage.OnChange(func(filename string) {
+		if doc, ok := storage.GetDocument(filename); ok {
+			autocomplete.AddDocument(filename, doc.Sentences)
+		}
+	})
+	snapshot := os.Getenv("CACHE_SNAPSHOT")
+	if snapshot != "" {
+		restored, kept, err := cache.LoadFile(snapshot, storage.DocumentHash)
+		if err != nil {
+			log.Println("Unable to load cache snapshot:", err)
+		} else {
+			log.Printf("Loaded cache snapshot: %d entries restored, %d waiting for their documents", restored, kept)
+		}
+	}
 
 	// Define routes
 	mux.Handle("/docs/", httpSwagger.WrapHandler)
 	mux.HandleFunc("/upload", handler.UploadHandler)
 	mux.HandleFunc("/files", handler.ListFilesHandler)
+	mux.HandleFunc("/document", handler.DocumentHandler)
 	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
 		handler.SearchHandler(w, r, cache)
 	})
 	mux.HandleFunc("/expand-context", handler.ExpandContextHandler)
+	mux.HandleFunc("/autocomplete", handler.AutocompleteHandler)
+	mux.HandleFunc("/synonyms", handler.SynonymsHandler)
+	mux.HandleFunc("/jobs/{id}", handler.JobHandler)
+	mux.HandleFunc("/admin/cache", func(w http.ResponseWriter, r *http.Request) {
+		handler.CacheHandler(w, r, cache)
+	})
 
 	loggedMux := handler.Logger(mux)
 
@@ -34,8 +110,30 @@ func main() {
 	log.Println("")
 	log.Println("SWAGGER DOCS HERE: http://localhost:8080/docs/index.html")
 
-	err := http.ListenAndServe(":8080", loggedMux)
-	if err != nil {
+	server := &http.Server{Addr: ":8080", Handler: loggedMux}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	err := server.ListenAndServe()
+	if err != http.ErrServerClosed {
 		log.Fatal("Server failed:", err)
 	}
+	<-stopped
+
+	// The cache is saved once searches have finished
+	if snapshot != "" {
+		n, err := cache.SaveFile(snapshot, storage.DocumentHash)
+		if err != nil {
+			log.Fatal("Unable to save cache snapshot: ", err)
+		}
+		log.Printf("Saved %d cache entries to %s", n, snapshot)
+	}
 }
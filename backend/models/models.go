@@ -1,8 +1,13 @@
 package models
 
+import "strings"
+
 type SearchRequest struct {
-	FileID string `json:"file_id"`
-	Query  string `json:"query"`
+	FileID     string `json:"file_id"`
+	Query      string `json:"query"`
+	Collection string `json:"collection,omitempty"` // synonym collection, "default" if empty
+	Language   string `json:"language,omitempty"`   // only match sentences in this language (ISO 639-1)
+	Field      string `json:"field,omitempty"`      // only match values of this record field
 }
 
 type ExpandContextRequest struct {
@@ -16,3 +21,28 @@ type SearchResult struct {
 	Match    string `json:"match"`
 	Distance int    `json:"distance"`
 }
+
+// Source locates a sentence in the original uploaded file. For formats
+// other than plain text, offsets and lines refer to the extracted text.
+type Source struct {
+	Offset    int `json:"offset"`         // byte offset of the sentence start
+	Length    int `json:"length"`         // length of the sentence in bytes
+	Line      int `json:"line"`           // 1-based line the sentence starts on
+	Paragraph int `json:"paragraph"`      // 0-based paragraph index
+	Page      int `json:"page,omitempty"` // 1-based page for paged formats such as PDF
+
+	Heading      string `json:"heading,omitempty"`       // nearest heading at or before the sentence
+	HeadingLevel int    `json:"heading_level,omitempty"` // level if the sentence is itself a heading
+
+	Record int    `json:"record,omitempty"` // 1-based row or object of CSV, TSV, JSON and NDJSON uploads
+	Field  string `json:"field,omitempty"`  // record field the sentence was taken from
+
+	Chapter      int    `json:"chapter,omitempty"`       // 1-based chapter of EPUB uploads, in reading order
+	ChapterTitle string `json:"chapter_title,omitempty"` // chapter title from the book's table of contents
+}
+
+// InField reports whether the record field name is field or nested in it,
+// e.g. "address.city" in "address"
+func InField(name, field string) bool {
+	return name == field || strings.HasPrefix(name, field+".")
+}
@@ -0,0 +1,40 @@
+package preprocess
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize applies Unicode NFKC normalization, which composes letters
+// followed by combining marks and replaces compatibility characters such
+// as ligatures and fullwidth forms with their plain equivalents. Unusual
+// spaces become plain spaces.
+func Normalize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf && unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, norm.NFKC.String(text))
+}
+
+// StripControl removes control and invisible formatting characters, such
+// as zero-width spaces, soft hyphens and byte order marks, and turns CRLF
+// and lone CR line endings into LF. Tabs and line breaks are kept.
+func StripControl(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r' || r == '\f' || r == '\v' || r == 0x85 || r == 0x2028 || r == 0x2029:
+			return '\n'
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,163 @@
+package preprocess
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/swanckel93/fuzzy_api/extract"
+)
+
+// Stage names, in the order they are listed by Names
+const (
+	StageNormalize    = "normalize"
+	StageStripControl = "strip_control"
+	StageDehyphenate  = "dehyphenate"
+	StageBoilerplate  = "boilerplate"
+	StageCollapse     = "collapse_whitespace"
+	StageRedact       = "redact"
+)
+
+// None disables preprocessing, including the default pipeline
+const None = "none"
+
+// stage transforms the blocks of an extracted document
+type stage func(blocks []extract.Block) []extract.Block
+
+var stages = map[string]stage{
+	StageNormalize:    perBlock(Normalize),
+	StageStripControl: perBlock(StripControl),
+	StageDehyphenate:  perBlock(Dehyphenate),
+	StageBoilerplate:  RemoveBoilerplate,
+	StageCollapse:     perBlock(CollapseWhitespace),
+	StageRedact:       perBlock(Redact),
+}
+
+// Names lists the available stages
+func Names() []string {
+	return []string{StageNormalize, StageStripControl, StageDehyphenate, StageBoilerplate, StageCollapse, StageRedact}
+}
+
+// Pipeline is a sequence of stages run in order
+type Pipeline struct {
+	names []string
+}
+
+// Default is the pipeline for uploads that do not choose one
+var Default Pipeline
+
+// Parse builds a pipeline from comma-separated stage names. "none" and the
+// empty string give an empty pipeline.
+func Parse(spec string) (Pipeline, error) {
+	var p Pipeline
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == None {
+			continue
+		}
+		if _, ok := stages[name]; !ok {
+			return Pipeline{}, fmt.Errorf("unknown preprocessing stage %q, expected one of: %s",
+				name, strings.Join(Names(), ", "))
+		}
+		p.names = append(p.names, name)
+	}
+	return p, nil
+}
+
+// Names returns the stages of the pipeline in the order they run
+func (p Pipeline) Names() []string {
+	return p.names
+}
+
+// Empty reports whether the pipeline has no stages
+func (p Pipeline) Empty() bool {
+	return len(p.names) == 0
+}
+
+// Apply runs every stage on blocks and drops blocks left without text
+func (p Pipeline) Apply(blocks []extract.Block) []extract.Block {
+	if p.Empty() {
+		return blocks
+	}
+	for _, name := range p.names {
+		blocks = stages[name](blocks)
+	}
+	kept := blocks[:0]
+	for _, b := range blocks {
+		if b.Text = strings.TrimSpace(b.Text); b.Text != "" {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// ApplyText runs the pipeline on text that is not split into blocks.
+// Stages that compare blocks, such as boilerplate removal, have no effect.
+func (p Pipeline) ApplyText(text string) string {
+	if p.Empty() {
+		return text
+	}
+	for _, name := range p.names {
+		text = stages[name]([]extract.Block{{Text: text}})[0].Text
+	}
+	return text
+}
+
+// Stream runs a pipeline on a text that is read in consecutive batches, so
+// that the batches together are rewritten like the whole text in a single
+// block by Apply. Batches must be cut at line breaks or spaces. Boilerplate
+// removal needs pages, which plain text does not have, so it has no effect
+// here either.
+type Stream struct {
+	p        Pipeline
+	collapse bool
+	started  bool // text was returned for an earlier batch
+	space    bool // the last batch returned ended with whitespace
+}
+
+// Stream returns a Stream running the pipeline
+func (p Pipeline) Stream() *Stream {
+	return &Stream{p: p, collapse: slices.Contains(p.names, StageCollapse)}
+}
+
+// Apply rewrites the next batch of the text, last is set for the final one
+func (s *Stream) Apply(batch string, last bool) string {
+	if s.p.Empty() {
+		return batch
+	}
+	for _, name := range s.p.names {
+		if name == StageCollapse {
+			// Only the ends of the whole text are trimmed
+			batch = collapseInner(batch)
+			continue
+		}
+		batch = stages[name]([]extract.Block{{Text: batch}})[0].Text
+	}
+
+	switch {
+	case !s.started:
+		batch = strings.TrimLeftFunc(batch, unicode.IsSpace)
+	case s.collapse && s.space:
+		// Whitespace was collapsed to the end of the previous batch
+		batch = strings.TrimLeft(batch, " \t")
+	}
+	if last {
+		batch = strings.TrimRightFunc(batch, unicode.IsSpace)
+	}
+	if batch != "" {
+		s.started = true
+		s.space = strings.ContainsAny(batch[len(batch)-1:], " \n")
+	}
+	return batch
+}
+
+// perBlock turns a text transformation into a stage
+func perBlock(f func(string) string) stage {
+	return func(blocks []extract.Block) []extract.Block {
+		for i := range blocks {
+			blocks[i].Text = f(blocks[i].Text)
+		}
+		return blocks
+	}
+}
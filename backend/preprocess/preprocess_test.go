@@ -0,0 +1,116 @@
+package preprocess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/swanckel93/fuzzy_api/extract"
+)
+
+func TestStages(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage func(string) string
+		input string
+		want  string
+	}{
+		{"normalize combining marks", Normalize, "Cafe\u0301 cre\u0300me", "Café crème"},
+		{"normalize ligatures", Normalize, "ﬁnal ofﬁce", "final office"},
+		{"normalize fullwidth", Normalize, "ＡＢＣ１２３", "ABC123"},
+		{"normalize spaces", Normalize, "a\u00a0b\u2009c\u3000d\ne", "a b c d\ne"},
+		{"normalize unmatched mark", Normalize, "x\u0301", "x\u0301"},
+		{"normalize other scripts", Normalize, "\u304b\u3099 \u1100\u1161 \u0418\u0306", "\u304c \uac00 \u0419"},
+		{"normalize compatibility", Normalize, "x\u00b2 \u2126 \u2460", "x2 \u03a9 1"},
+		{"strip controls", StripControl, "a\x00b\u200bc\u00add\ufeffe\tf", "abcde\tf"},
+		{"strip line endings", StripControl, "one\r\ntwo\rthree\ffour", "one\ntwo\nthree\nfour"},
+		{"dehyphenate", Dehyphenate, "inter-\nnational and co-\n  operation", "international and cooperation"},
+		{"dehyphenate keeps capitals", Dehyphenate, "Baden-\nWürttemberg", "Baden-\nWürttemberg"},
+		{"dehyphenate keeps dashes", Dehyphenate, "a list -\nitem", "a list -\nitem"},
+		{"collapse whitespace", CollapseWhitespace, "  a \t b  \n  c\n\n\n\nd  ", "a b\nc\n\nd"},
+		{"redact email", Redact, "Write to jane.doe+x@mail.example.org today", "Write to [EMAIL] today"},
+		{"redact IBAN", Redact, "IBAN DE89 3704 0044 0532 0130 00.", "IBAN [IBAN]."},
+		{"redact card", Redact, "Card 4111 1111 1111 1111 expired", "Card [CARD] expired"},
+		{"keep non-card number", Redact, "Order 1234 5678 9012 3456 shipped", "Order 1234 5678 9012 3456 shipped"},
+		{"redact phone", Redact, "Call +49 30 1234567 or (555) 123-4567 or 555.123.4567", "Call [PHONE] or [PHONE] or [PHONE]"},
+		{"keep dates", Redact, "On 2024-01-15 at 10:30", "On 2024-01-15 at 10:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stage(tt.input); got != tt.want {
+				t.Errorf("Test %q failed. Got: %q", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestRemoveBoilerplate(t *testing.T) {
+	page := func(n int, body string) extract.Block {
+		return extract.Block{
+			Text: "ACME Corp – Annual Report\n" + body + "\nPage " + string(rune('0'+n)) + " of 4",
+			Page: n,
+		}
+	}
+	blocks := []extract.Block{
+		page(1, "Revenue grew."),
+		page(2, "Costs fell."),
+		page(3, "Outlook is good."),
+		page(4, "Thanks."),
+	}
+	got := RemoveBoilerplate(blocks)
+	want := []string{"Revenue grew.", "Costs fell.", "Outlook is good.", "Thanks."}
+	for i, b := range got {
+		if b.Text != want[i] {
+			t.Errorf("Test %q failed. Got: %q", "page "+string(rune('1'+i)), b.Text)
+		}
+	}
+
+	// Blocks without pages are never boilerplate
+	records := []extract.Block{{Text: "yes"}, {Text: "yes"}, {Text: "yes"}, {Text: "yes"}}
+	if got := RemoveBoilerplate(records); got[0].Text != "yes" {
+		t.Errorf("Test %q failed. Got: %+v", "records", got)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	p, err := Parse(" Normalize, collapse_whitespace ,redact")
+	if err != nil {
+		t.Fatalf("Test %q failed. Error: %v", "parse", err)
+	}
+	if got := p.Names(); !reflect.DeepEqual(got, []string{StageNormalize, StageCollapse, StageRedact}) {
+		t.Errorf("Test %q failed. Got: %v", "names", got)
+	}
+
+	blocks := []extract.Block{{Text: "Mail  \ufb01ona@example.com"}, {Text: " \u3000 "}, {Text: "ok"}}
+	got := p.Apply(blocks)
+	want := []extract.Block{{Text: "Mail [EMAIL]"}, {Text: "ok"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Test %q failed. Got: %+v", "apply", got)
+	}
+	if got := p.ApplyText("a  b"); got != "a b" {
+		t.Errorf("Test %q failed. Got: %q", "apply text", got)
+	}
+
+	if p, err := Parse("none"); err != nil || !p.Empty() {
+		t.Errorf("Test %q failed. Got: %v, %v", "none", p, err)
+	}
+	if _, err := Parse("normalize,shout"); err == nil {
+		t.Errorf("Test %q failed. Expected an error", "unknown stage")
+	}
+}
+
+func TestStream(t *testing.T) {
+	p, _ := Parse("collapse_whitespace")
+	batches := []string{" \n First  line. \n \n\n", "Second\t line. \n", "\t third line.  \n\n"}
+
+	s := p.Stream()
+	got := ""
+	for i, b := range batches {
+		got += s.Apply(b, i == len(batches)-1)
+	}
+	want := p.Apply([]extract.Block{{Text: strings.Join(batches, "")}})[0].Text
+	if got != want {
+		t.Errorf("Test %q failed. Got: %q, expected %q", "stream", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package preprocess
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/swanckel93/fuzzy_api/extract"
+)
+
+// hyphenBreak matches a word broken over two lines with a hyphen
+var hyphenBreak = regexp.MustCompile(`(\pL)[-\x{00AD}\x{2010}][ \t]*\r?\n[ \t]*(\p{Ll})`)
+
+// Dehyphenate joins words that were hyphenated at the end of a line, as in
+// "inter-\nnational". Hyphens followed by a capital letter are kept.
+func Dehyphenate(text string) string {
+	return hyphenBreak.ReplaceAllString(text, "$1$2")
+}
+
+var (
+	spaceRun    = regexp.MustCompile(`[ \t]+`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+	lineSpacing = regexp.MustCompile(` ?\n ?`)
+)
+
+// CollapseWhitespace turns runs of spaces and tabs into a single space,
+// trims lines and keeps at most one blank line between paragraphs
+func CollapseWhitespace(text string) string {
+	return strings.TrimSpace(collapseInner(text))
+}
+
+// collapseInner is CollapseWhitespace without trimming the ends of text,
+// for parts of a longer text
+func collapseInner(text string) string {
+	text = spaceRun.ReplaceAllString(text, " ")
+	text = lineSpacing.ReplaceAllString(text, "\n")
+	return blankLines.ReplaceAllString(text, "\n\n")
+}
+
+// Redaction markers that replace personal data
+const (
+	RedactedEmail = "[EMAIL]"
+	RedactedIBAN  = "[IBAN]"
+	RedactedCard  = "[CARD]"
+	RedactedPhone = "[PHONE]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	ibanPattern  = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,5}){1,4}|\(\d{2,4}\)[ .-]?\d{3,4}[ .-]?\d{3,4}|\b\d{3}[.-]\d{3}[.-]\d{4})\b`)
+)
+
+// Redact replaces email addresses, IBANs, payment card numbers and phone
+// numbers with markers such as "[EMAIL]"
+func Redact(text string) string {
+	text = emailPattern.ReplaceAllString(text, RedactedEmail)
+	text = ibanPattern.ReplaceAllString(text, RedactedIBAN)
+	text = cardPattern.ReplaceAllStringFunc(text, func(s string) string {
+		if luhn(s) {
+			return RedactedCard
+		}
+		return s
+	})
+	return phonePattern.ReplaceAllString(text, RedactedPhone)
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of card numbers
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// boilerplateLines is how many lines at the top and bottom of a page are
+// considered as running headers and footers
+const boilerplateLines = 2
+
+// minBoilerplatePages is how many pages a document needs before lines
+// repeated across them are taken as headers and footers
+const minBoilerplatePages = 3
+
+// digitRun matches numbers that differ between otherwise repeated lines,
+// such as page numbers
+var digitRun = regexp.MustCompile(`\d+`)
+
+// RemoveBoilerplate drops running headers and footers: lines at the top or
+// bottom of pages that repeat on at least half of them. Numbers are ignored
+// when comparing lines, so "Page 3 of 10" matches "Page 4 of 10". Blocks
+// without a page, as extracted from formats other than PDF, are left as is.
+func RemoveBoilerplate(blocks []extract.Block) []extract.Block {
+	pages := 0
+	for _, b := range blocks {
+		if b.Page > 0 {
+			pages++
+		}
+	}
+	if pages < minBoilerplatePages {
+		return blocks
+	}
+
+	lines := make([][]string, len(blocks))
+	counts := make(map[string]int)
+	for i, b := range blocks {
+		if b.Page == 0 {
+			continue
+		}
+		lines[i] = strings.Split(b.Text, "\n")
+		seen := make(map[string]bool)
+		for _, j := range edgeLines(lines[i]) {
+			key := boilerplateKey(lines[i][j])
+			if !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+
+	threshold := max(minBoilerplatePages, (pages+1)/2)
+	for i := range blocks {
+		drop := make(map[int]bool)
+		for _, j := range edgeLines(lines[i]) {
+			if counts[boilerplateKey(lines[i][j])] >= threshold {
+				drop[j] = true
+			}
+		}
+		if len(drop) == 0 {
+			continue
+		}
+		kept := make([]string, 0, len(lines[i]))
+		for j, l := range lines[i] {
+			if !drop[j] {
+				kept = append(kept, l)
+			}
+		}
+		blocks[i].Text = strings.Join(kept, "\n")
+	}
+	return blocks
+}
+
+// edgeLines returns the indexes of the first and last non-blank lines
+func edgeLines(lines []string) []int {
+	var first, last []int
+	for j := 0; j < len(lines) && len(first) < boilerplateLines; j++ {
+		if strings.TrimSpace(lines[j]) != "" {
+			first = append(first, j)
+		}
+	}
+	for j := len(lines) - 1; j >= 0 && len(last) < boilerplateLines; j-- {
+		if strings.TrimSpace(lines[j]) != "" && !slices.Contains(first, j) {
+			last = append(last, j)
+		}
+	}
+	return append(first, last...)
+}
+
+// boilerplateKey is the form lines are compared in
+func boilerplateKey(line string) string {
+	return digitRun.ReplaceAllString(strings.Join(strings.Fields(line), " "), "#")
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxCandidates caps the number of sentences kept to refine a search
+const maxCandidates = 1024
+
+// unbounded is the threshold of candidates that hold every matching sentence
+const unbounded = math.MaxInt
+
+// Candidates are the sentences a search for a longer query starting with
+// Query can be refined from: every sentence within Threshold of Query
+type Candidates struct {
+	Query     string
+	Indices   []int // sentence indices, closest first
+	Threshold int   // edit distance, math.MaxInt if Indices holds every match
+}
+
+// FuzzySearchCandidates is FuzzySearch that also returns the candidates
+// later searches for extensions of query can be refined from, or nil if
+// too many sentences are equally close to keep any
+func FuzzySearchCandidates(query string, sentences []string) ([]SearchResult, *Candidates) {
+	matches := scanSentences(query, sentences)
+	if len(matches) > 0 {
+		SortSearchResults(matches)
+	}
+	return firstResults(matches), newCandidates(query, matches, unbounded)
+}
+
+// RefineSearch returns FuzzySearch(query, sentences) computed from the
+// candidates of a search for a prefix of query, by scanning only those.
+// It also returns the candidates for the next refinement. ok is false when
+// the results cannot be guaranteed to equal a full search, and the caller
+// has to search all sentences.
+//
+// Extending a query by k bytes lowers the edit distance of a sentence by
+// at most k, so a sentence that is not a candidate is further from query
+// than the candidate threshold minus k. Results within that bound cannot
+// be displaced by sentences outside the candidates.
+func RefineSearch(query string, cand *Candidates, sentences []string) (results []SearchResult, next *Candidates, ok bool) {
+	if cand == nil || cand.Query == "" || len(query) <= len(cand.Query) || !strings.HasPrefix(query, cand.Query) {
+		return nil, nil, false
+	}
+	threshold := cand.Threshold
+	if threshold != unbounded {
+		k := max(len(query)-len(cand.Query), len(strings.ToLower(query))-len(strings.ToLower(cand.Query)))
+		if threshold -= k; threshold < 0 {
+			return nil, nil, false
+		}
+	}
+
+	subset := make([]string, len(cand.Indices))
+	for i, idx := range cand.Indices {
+		if idx < 0 || idx >= len(sentences) {
+			return nil, nil, false
+		}
+		subset[i] = sentences[idx]
+	}
+	matches := scanSentences(query, subset)
+	for i := range matches {
+		matches[i].SentenceIndex = cand.Indices[matches[i].SentenceIndex]
+	}
+	if len(matches) > 0 {
+		SortSearchResults(matches)
+	}
+
+	results = firstResults(matches)
+	if threshold != unbounded && (len(results) < maxResults || results[len(results)-1].Distance > threshold) {
+		return nil, nil, false // sentences outside the candidates may rank
+	}
+	return results, newCandidates(query, matches, threshold), true
+}
+
+// newCandidates keeps the sorted matches within threshold of query,
+// lowering threshold until at most maxCandidates remain
+func newCandidates(query string, matches []SearchResult, threshold int) *Candidates {
+	beyond := func(i int) bool { return matches[i].Distance > threshold }
+	n := sort.Search(len(matches), beyond)
+	if n > maxCandidates {
+		// Sentences at the same distance are kept or dropped together
+		threshold = matches[maxCandidates].Distance - 1
+		n = sort.Search(maxCandidates, beyond)
+	}
+	if threshold < 0 {
+		return nil
+	}
+
+	cand := &Candidates{Query: query, Indices: make([]int, n), Threshold: threshold}
+	for i, m := range matches[:n] {
+		cand.Indices[i] = m.SentenceIndex
+	}
+	return cand
+}
+
+// firstResults returns the first maxResults of sorted results
+func firstResults(results []SearchResult) []SearchResult {
+	if len(results) > maxResults {
+		return results[:maxResults]
+	}
+	return results
+}
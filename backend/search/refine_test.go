@@ -0,0 +1,133 @@
+package search
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRefineSearch(t *testing.T) {
+	many := func(s string, n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprint(s, " ", i)
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		query     string
+		prefix    string
+		sentences []string
+		wantOK    bool
+	}{
+		{"all matches kept", "invoice", "invo", []string{"the invoice", "an invitation", "no"}, true},
+		{"not a prefix", "invoice", "involve", []string{"the invoice"}, false},
+		{"same query", "inv", "inv", []string{"the invoice"}, false},
+		{"within threshold", "invoice", "invoic", append(many("invoice due", 20), many("paid in full", maxCandidates)...), true},
+		{"beyond threshold", "invoicexyzw", "invoic", append(many("invoice due", 20), many("paid in full", maxCandidates)...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, cand := FuzzySearchCandidates(tt.prefix, tt.sentences)
+			got, next, ok := RefineSearch(tt.query, cand, tt.sentences)
+			if ok != tt.wantOK {
+				t.Fatalf("Test %q failed. Refined: %v, candidates: %+v", tt.name, ok, cand)
+			}
+			if !ok {
+				return
+			}
+			if want := FuzzySearch(tt.query, tt.sentences); !reflect.DeepEqual(got, want) {
+				t.Errorf("Test %q failed. Got: %+v, want %+v", tt.name, got, want)
+			}
+			if next == nil || next.Query != tt.query {
+				t.Errorf("Test %q failed. Next candidates: %+v", tt.name, next)
+			}
+		})
+	}
+}
+
+func TestFuzzySearchCandidates(t *testing.T) {
+	sentences := make([]string, 2*maxCandidates)
+	for i := range sentences {
+		sentences[i] = "word"
+		if i%2 == 0 {
+			sentences[i] = "wore"
+		}
+	}
+	sentences = append(sentences, "nothing alike")
+
+	results, cand := FuzzySearchCandidates("word", sentences)
+	if !reflect.DeepEqual(results, FuzzySearch("word", sentences)) {
+		t.Errorf("Test %q failed. Results differ from FuzzySearch", "results")
+	}
+	// Only the exact matches fit, ties at distance 1 are all dropped
+	if cand == nil || cand.Threshold != 0 || len(cand.Indices) != maxCandidates {
+		t.Fatalf("Test %q failed. Got threshold %d, %d indices", "cap", cand.Threshold, len(cand.Indices))
+	}
+
+	if _, cand := FuzzySearchCandidates("word", sentences[:10]); cand.Threshold != unbounded || len(cand.Indices) != 10 {
+		t.Errorf("Test %q failed. Got: %+v", "all", cand)
+	}
+	same := make([]string, maxCandidates+1)
+	for i := range same {
+		same[i] = "word"
+	}
+	if _, cand := FuzzySearchCandidates("word", same); cand != nil {
+		t.Errorf("Test %q failed. Got: %+v", "too many ties", cand)
+	}
+}
+
+// TestRefineSearchMatchesFullSearch types random queries character by
+// character and checks that every refinement equals a full search
+func TestRefineSearchMatchesFullSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"invoice", "invoices", "involve", "inventory", "vote", "voice", "in", "order", "paid", "due", "Invoke"}
+	sentence := func() string {
+		n := 1 + rng.Intn(6)
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = words[rng.Intn(len(words))]
+		}
+		return strings.Join(parts, " ")
+	}
+
+	refined, fallbacks := 0, 0
+	for round := range 40 {
+		// Large documents have more matches than are kept as candidates
+		size := 1 + rng.Intn(60)
+		if round%2 == 0 {
+			size = maxCandidates + rng.Intn(maxCandidates)
+		}
+		sentences := make([]string, size)
+		for i := range sentences {
+			sentences[i] = sentence()
+		}
+		query := words[rng.Intn(len(words))]
+		if rng.Intn(3) == 0 {
+			query += " " + words[rng.Intn(len(words))]
+		}
+
+		_, cand := FuzzySearchCandidates(query[:1], sentences)
+		for n := 2; n <= len(query); n++ {
+			want, wantCand := FuzzySearchCandidates(query[:n], sentences)
+			got, next, ok := RefineSearch(query[:n], cand, sentences)
+			if ok {
+				refined++
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("Test %q failed. Got: %+v, want %+v", fmt.Sprint(round, query[:n]), got, want)
+				}
+				cand = next
+			} else {
+				fallbacks++
+				cand = wantCand
+			}
+		}
+	}
+	if refined == 0 || fallbacks == 0 {
+		t.Errorf("Test %q failed. Refined %d, fell back %d times", "coverage", refined, fallbacks)
+	}
+}
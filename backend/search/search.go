@@ -4,19 +4,104 @@ import (
 	"strings"
 	"sync"
 	"sort"
+	"unicode/utf8"
+
 	"github.com/agnivade/levenshtein"
+	"github.com/swanckel93/fuzzy_api/analysis"
+	"github.com/swanckel93/fuzzy_api/models"
 )
 
 // SearchResult represents a fuzzy match result
 type SearchResult struct {
-	Sentence string `json:"sentence"`
-	Index    int    `json:"index"`
-	Match    string `json:"match"`
-	Distance int    `json:"distance"`
+	Sentence      string `json:"sentence"`
+	SentenceIndex int    `json:"sentence_index"`
+	Index         int    `json:"index"`
+	Match         string `json:"match"`
+	Distance      int    `json:"distance"`
+	Expansion     string `json:"expansion,omitempty"` // synonym rule that produced the match
+	models.Source        // where the sentence is in the original file
+
+	Values map[string]any `json:"values,omitempty"` // fields of the original record for structured formats
 }
 
+// Variant is an alternative form of a query, e.g. produced by synonym
+// expansion. Expansion is copied onto the results the variant produced.
+type Variant struct {
+	Query     string
+	Expansion string
+}
+
+// maxResults is the number of results returned by a search
+const maxResults = 10
+
 // FuzzySearch performs a fuzzy search for a query in a slice of sentences using goroutines.
 func FuzzySearch(query string, sentences []string) []SearchResult {
+	results := scanSentences(query, sentences)
+	return topResults(results)
+}
+
+// FuzzySearchVariants searches every query variant and keeps the best
+// match per sentence, so each sentence appears at most once.
+func FuzzySearchVariants(variants []Variant, sentences []string) []SearchResult {
+	return mergeVariants(variants, func(query string) []SearchResult {
+		return scanSentences(query, sentences)
+	})
+}
+
+// TokenSearch scores sentences term by term: every analyzed query term is
+// matched against the closest analyzed term of the sentence and the distances
+// are summed. Queries that analyze to no terms (e.g. only stop words) fall
+// back to character-level matching.
+func TokenSearch(variants []Variant, analyzer *analysis.Analyzer, sentences []string, tokens [][]analysis.Token) []SearchResult {
+	return mergeVariants(variants, func(query string) []SearchResult {
+		terms := analyzer.Terms(query)
+		if len(terms) == 0 {
+			return scanSentences(query, sentences)
+		}
+		return scan(sentences, func(idx int, s string) (SearchResult, bool) {
+			result, ok := matchTokens(terms, s, tokens[idx])
+			result.SentenceIndex = idx
+			return result, ok
+		})
+	})
+}
+
+// mergeVariants runs search for every variant and keeps the best result per sentence
+func mergeVariants(variants []Variant, search func(query string) []SearchResult) []SearchResult {
+	best := make(map[int]SearchResult)
+	for _, v := range variants {
+		for _, r := range search(v.Query) {
+			r.Expansion = v.Expansion
+			if prev, ok := best[r.SentenceIndex]; !ok || r.Distance < prev.Distance {
+				best[r.SentenceIndex] = r
+			}
+		}
+	}
+
+	results := make([]SearchResult, 0, len(best))
+	for _, r := range best {
+		results = append(results, r)
+	}
+	return topResults(results)
+}
+
+// scanSentences matches query against every sentence concurrently and
+// returns all matches, unsorted
+func scanSentences(query string, sentences []string) []SearchResult {
+	return scan(sentences, func(idx int, s string) (SearchResult, bool) {
+		bestMatch, bestIndex, bestDist := findBestFuzzyMatch(query, s)
+		return SearchResult{
+			Sentence:      s,
+			SentenceIndex: idx,
+			Index:         bestIndex,
+			Match:         bestMatch,
+			Distance:      bestDist,
+		}, bestMatch != ""
+	})
+}
+
+// scan runs match on every sentence using goroutines and collects the matches
+func scan(sentences []string, match func(idx int, s string) (SearchResult, bool)) []SearchResult {
 	var wg sync.WaitGroup
 	resultsChan := make(chan SearchResult, len(sentences))
 
@@ -25,14 +110,8 @@ func FuzzySearch(query string, sentences []string) []SearchResult {
 
 		go func(idx int, s string) {
 			defer wg.Done()
-			bestMatch, bestIndex, bestDist := findBestFuzzyMatch(query, s)
-			if bestMatch != "" {
-				resultsChan <- SearchResult{
-					Sentence: s,
-					Index:    bestIndex,
-					Match:    bestMatch,
-					Distance: bestDist,
-				}
+			if result, ok := match(idx, s); ok {
+				resultsChan <- result
 			}
 		}(i, sentence)
 	}
@@ -44,11 +123,56 @@ func FuzzySearch(query string, sentences []string) []SearchResult {
 	for result := range resultsChan {
 		results = append(results, result)
 	}
+	return results
+}
+
+// matchTokens sums, over the query terms, the distance to the closest sentence
+// term. A term with nothing close costs its own length. The closest sentence
+// token overall is reported as the match.
+func matchTokens(terms []string, sentence string, tokens []analysis.Token) (SearchResult, bool) {
+	if len(tokens) == 0 {
+		return SearchResult{}, false
+	}
+
+	total := 0
+	matched := false
+	bestTok, bestDist := tokens[0], -1
+	for _, term := range terms {
+		termLen := utf8.RuneCountInString(term)
+		termBest := termLen
+		for _, tok := range tokens {
+			dist := levenshtein.ComputeDistance(term, tok.Term)
+			if dist < termBest {
+				termBest = dist
+			}
+			if bestDist == -1 || dist < bestDist {
+				bestTok, bestDist = tok, dist
+			}
+		}
+		if termBest < termLen {
+			matched = true
+		}
+		total += termBest
+	}
+	if !matched {
+		return SearchResult{}, false
+	}
+
+	return SearchResult{
+		Sentence: sentence,
+		Index:    bestTok.Start,
+		Match:    sentence[bestTok.Start:bestTok.End],
+		Distance: total,
+	}, true
+}
+
+// topResults sorts results and keeps the best maxResults
+func topResults(results []SearchResult) []SearchResult {
 	if len(results) > 0 {
 		SortSearchResults(results)
 	}
-	if len(results)> 10 {
-		return results[:10]
+	if len(results) > maxResults {
+		return results[:maxResults]
 	}
 	return results
 }
@@ -82,8 +206,10 @@ func SortSearchResults(searchResults []SearchResult) {
         switch {
         case mi.Distance != mj.Distance:
             return mi.Distance < mj.Distance
-        default:
+        case mi.Index != mj.Index:
             return mi.Index < mj.Index
+        default:
+            return mi.SentenceIndex < mj.SentenceIndex
         }
     })
 }
\ No newline at end of file
@@ -2,6 +2,8 @@ package search
 
 import (
 	"testing"
+
+	"github.com/swanckel93/fuzzy_api/analysis"
 )
 
 func TestFuzzySearch(t *testing.T) {
@@ -99,4 +101,30 @@ func TestFuzzySearchOrdering(t *testing.T) {
 			t.Errorf("Results not sorted by index when distances equal at index %d: got %d > %d", i, a.Index, b.Index)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestTokenSearch(t *testing.T) {
+	analyzer, _ := analysis.Get("english")
+	sentences := []string{
+		"The dog runs home.",
+		"The cat sleeps all day.",
+		"The the the the.",
+	}
+	tokens := make([][]analysis.Token, len(sentences))
+	for i, s := range sentences {
+		tokens[i] = analyzer.Analyze(s)
+	}
+
+	results := TokenSearch([]Variant{{Query: "the running dogs"}}, analyzer, sentences, tokens)
+
+	if len(results) == 0 {
+		t.Fatalf("Expected results, got none")
+	}
+	if results[0].SentenceIndex != 0 || results[0].Distance != 0 {
+		t.Errorf("Expected exact stemmed match on sentence 0, got %+v", results[0])
+	}
+	for _, r := range results {
+		if r.SentenceIndex == 2 {
+			t.Errorf("Stop words only sentence should not match, got %+v", r)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package searchCache
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+// errPanicked is returned to calls that waited for a computation that panicked
+var errPanicked = errors.New("search computation panicked")
+
+// call is a computation of search results that is in flight
+type call struct {
+	done    chan struct{} // closed once results and err are set
+	results []search.SearchResult
+	err     error
+}
+
+// flightKey identifies a computation by its key and the versions of the
+// cache and the document it started with
+type flightKey struct {
+	key             CacheKey
+	epoch, revision uint64
+}
+
+// flights tracks the computations in flight by key
+type flights struct {
+	mu        sync.Mutex
+	calls     map[flightKey]*call
+	coalesced uint64            // calls that waited for another one instead of computing
+	epoch     uint64            // incremented by Clear
+	revisions map[string]uint64 // incremented by Invalidate, by document
+}
+
+// flightKey returns the key of a computation started now. f.mu must be held.
+func (f *flights) flightKey(key CacheKey) flightKey {
+	return flightKey{key: key, epoch: f.epoch, revision: f.revisions[key.DocID]}
+}
+
+// invalidate makes the computations in flight for a document stale
+func (f *flights) invalidate(docID string) {
+	f.mu.Lock()
+	if f.revisions == nil {
+		f.revisions = make(map[string]uint64)
+	}
+	f.revisions[docID]++
+	f.mu.Unlock()
+}
+
+// clear makes all computations in flight stale
+func (f *flights) clear() {
+	f.mu.Lock()
+	f.epoch++
+	f.mu.Unlock()
+}
+
+// Do returns the cached results for a document and query, or computes them
+// with compute on a miss. Concurrent calls for the same key share a single
+// computation: the first one runs compute, the others wait for its results.
+// Results are cached once, with the candidates compute returns, and only if
+// compute succeeds and the document was not invalidated meanwhile, since
+// compute may have searched the replaced version; calls made after the
+// invalidation do not share such a computation either. cached reports whether the results came from the cache
+// rather than a computation.
+func (c *SearchCache) Do(docID, query string, compute func() ([]search.SearchResult, *search.Candidates, error)) (results []search.SearchResult, cached bool, err error) {
+	if results, ok := c.Get(docID, query); ok {
+		return results, true, nil
+	}
+
+	key := CacheKey{DocID: docID, Query: query}
+	f := &c.flights
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[flightKey]*call)
+	}
+	fk := f.flightKey(key)
+	if cl, ok := f.calls[fk]; ok {
+		f.coalesced++
+		f.mu.Unlock()
+		<-cl.done
+		return cl.results, false, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	f.calls[fk] = cl
+	f.mu.Unlock()
+
+	// The call is finished even if compute panics, so waiting calls
+	// are not blocked forever
+	defer func() {
+		f.mu.Lock()
+		delete(f.calls, fk)
+		f.mu.Unlock()
+		close(cl.done)
+	}()
+	cl.err = errPanicked
+	var candidates *search.Candidates
+	cl.results, candidates, cl.err = compute()
+	if cl.err == nil {
+		// Cached before the call is removed, so later calls find it. The
+		// lock keeps Invalidate and Clear from running in between the check
+		// and the set; they make the flight stale before removing entries.
+		value := c.compact(cl.results)
+		f.mu.Lock()
+		if f.flightKey(key) == fk {
+			c.shardFor(key).set(key, value, candidates, c.expiry(c.ttl))
+		}
+		f.mu.Unlock()
+	}
+	return cl.results, false, cl.err
+}
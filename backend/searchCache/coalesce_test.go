@@ -0,0 +1,140 @@
+package searchCache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+func TestDoCoalesces(t *testing.T) {
+	c := NewSearchCache(1)
+	var computed atomic.Int32
+	release := make(chan struct{})
+	compute := func() ([]search.SearchResult, *search.Candidates, error) {
+		computed.Add(1)
+		<-release
+		return results(2, 10), nil, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	got := make([][]search.SearchResult, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], _, _ = c.Do("doc", "popular", compute)
+		}()
+	}
+	// Let every caller reach Do before the computation ends
+	for c.Stats().Coalesced < callers-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := computed.Load(); n != 1 {
+		t.Errorf("Test %q failed. Computed %d times", "coalesce", n)
+	}
+	for i, r := range got {
+		if len(r) != 2 {
+			t.Errorf("Test %q failed. Caller %d got: %+v", "coalesce", i, r)
+		}
+	}
+	if c.Len() != 1 {
+		t.Errorf("Test %q failed. Got %d entries", "cached once", c.Len())
+	}
+	if _, cached, _ := c.Do("doc", "popular", compute); !cached || computed.Load() != 1 {
+		t.Errorf("Test %q failed. Later call was computed again", "cached")
+	}
+}
+
+func TestDoErrors(t *testing.T) {
+	c := NewSearchCache(1)
+	errSearch := errors.New("no such document")
+	_, _, err := c.Do("doc", "q", func() ([]search.SearchResult, *search.Candidates, error) { return nil, nil, errSearch })
+	if !errors.Is(err, errSearch) || c.Len() != 0 {
+		t.Errorf("Test %q failed. Got: %v, %d entries", "error", err, c.Len())
+	}
+
+	// A panicking computation does not block callers waiting for it
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		c.Do("doc", "boom", func() ([]search.SearchResult, *search.Candidates, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	done := make(chan error)
+	go func() {
+		_, _, err := c.Do("doc", "boom", func() ([]search.SearchResult, *search.Candidates, error) { return results(1, 10), nil, nil })
+		done <- err
+	}()
+	for c.Stats().Coalesced == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if !errors.Is(err, errPanicked) {
+			t.Errorf("Test %q failed. Got: %v", "panic", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Test %q failed. Waiting caller is blocked", "panic")
+	}
+}
+
+// TestDoInvalidated checks that results computed while their document was
+// replaced are neither cached nor shared with later calls
+func TestDoInvalidated(t *testing.T) {
+	c := NewSearchCache(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan []search.SearchResult)
+	go func() {
+		r, _, _ := c.Do("doc", "q", func() ([]search.SearchResult, *search.Candidates, error) {
+			close(started)
+			<-release
+			return results(1, 10), nil, nil
+		})
+		done <- r
+	}()
+	<-started
+	c.Invalidate("doc")
+
+	r, _, _ := c.Do("doc", "q", func() ([]search.SearchResult, *search.Candidates, error) { return results(2, 10), nil, nil })
+	if len(r) != 2 || c.Stats().Coalesced != 0 {
+		t.Errorf("Test %q failed. Got: %+v", "not shared", r)
+	}
+	close(release)
+	if r := <-done; len(r) != 1 {
+		t.Errorf("Test %q failed. Got: %+v", "stale call", r)
+	}
+	if r, ok := c.Get("doc", "q"); !ok || len(r) != 2 {
+		t.Errorf("Test %q failed. Got: %+v, %v", "not cached", r, ok)
+	}
+
+	// The same goes for computations in flight when the cache is cleared
+	started, release = make(chan struct{}), make(chan struct{})
+	go func() {
+		r, _, _ := c.Do("doc", "cleared", func() ([]search.SearchResult, *search.Candidates, error) {
+			close(started)
+			<-release
+			return results(1, 10), nil, nil
+		})
+		done <- r
+	}()
+	<-started
+	c.Clear()
+	close(release)
+	<-done
+	if c.Len() != 0 {
+		t.Errorf("Test %q failed. Got %d entries", "cleared", c.Len())
+	}
+}
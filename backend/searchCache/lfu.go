@@ -0,0 +1,101 @@
+package searchCache
+
+import "container/heap"
+
+// lfu evicts the least frequently used entries, the least recently used
+// first among equally frequent ones. A scan of many unique queries only
+// evicts entries that were never looked up again, but entries that were
+// popular once stay until they are invalidated.
+type lfu struct {
+	maxSize, size int
+	clock         uint64
+	nodes         map[CacheKey]*node
+	heap          nodeHeap
+}
+
+func newLFU(maxSize int) Policy {
+	return &lfu{maxSize: maxSize, nodes: make(map[CacheKey]*node)}
+}
+
+func (p *lfu) Access(key CacheKey) {
+	if n, ok := p.nodes[key]; ok {
+		p.clock++
+		n.freq++
+		n.tick = p.clock
+		heap.Fix(&p.heap, n.index)
+	}
+}
+
+func (p *lfu) Add(key CacheKey, size int) []CacheKey {
+	p.clock++
+	n := &node{key: key, size: size, tick: p.clock}
+	p.nodes[key] = n
+	heap.Push(&p.heap, n)
+	p.size += size
+
+	var evicted []CacheKey
+	for p.size > p.maxSize {
+		// The new entry competes with the others, so that a scan evicts
+		// its own entries rather than ones that were looked up before
+		victim := p.heap[0]
+		p.Remove(victim.key)
+		evicted = append(evicted, victim.key)
+	}
+	return evicted
+}
+
+func (p *lfu) Remove(key CacheKey) {
+	if n, ok := p.nodes[key]; ok {
+		heap.Remove(&p.heap, n.index)
+		delete(p.nodes, key)
+		p.size -= n.size
+	}
+}
+
+func (p *lfu) Keys() []CacheKey {
+	// Popping from a copy gives the eviction order
+	h := make(nodeHeap, len(p.heap))
+	copy(h, p.heap)
+	keys := make([]CacheKey, 0, len(h))
+	for h.Len() > 0 {
+		n := heap.Pop(&h).(*node)
+		keys = append(keys, n.key)
+	}
+	// Popping moved the nodes, so their positions are restored
+	for i, n := range p.heap {
+		n.index = i
+	}
+	return keys
+}
+
+// nodeHeap orders nodes by frequency, then by their last access
+type nodeHeap []*node
+
+func (h nodeHeap) Len() int { return len(h) }
+
+func (h nodeHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h nodeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *nodeHeap) Push(x any) {
+	n := x.(*node)
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+
+func (h *nodeHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,134 @@
+package searchCache
+
+import (
+	"container/list"
+	"encoding/json"
+	"unsafe"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+// Fixed sizes of the parts of an entry, as laid out in memory
+var (
+	// a map slot holds the key, a pointer and a control byte
+	mapSlotSize = int(unsafe.Sizeof(CacheKey{}) + unsafe.Sizeof(&node{}) + 1)
+	// the policies index their nodes by key, mostly in lists
+	policyOverhead = mapSlotSize + mapEntryOverhead + int(unsafe.Sizeof(node{})+unsafe.Sizeof(list.Element{}))
+	entryOverhead  = int(unsafe.Sizeof(cacheEntry{})) + policyOverhead
+	resultSize     = int(unsafe.Sizeof(search.SearchResult{}))
+	candidatesSize = int(unsafe.Sizeof(search.Candidates{}))
+	intSize        = int(unsafe.Sizeof(0))
+	stringSize     = int(unsafe.Sizeof(""))
+	ifaceSize      = int(unsafe.Sizeof(any(nil)))
+	// per-entry share of a map's slots, control bytes and buckets,
+	// assuming maps are about 80% full
+	mapEntryOverhead = 8
+	mapHeaderSize    = 48
+)
+
+// mapSize returns the bytes of a map of n entries whose key and value take
+// slotSize bytes: its header and its groups of eight slots with a control
+// byte each. Empty maps have no groups yet, small ones a single group,
+// and larger ones are at most 7/8 full.
+func mapSize(n, slotSize int) int {
+	if n == 0 {
+		return mapHeaderSize
+	}
+	slots := 8
+	for n > 8 && slots*7/8 < n {
+		slots *= 2
+	}
+	return mapHeaderSize + slots*(slotSize+1)
+}
+
+// Resolver fills in the sentence, source and record values of results of
+// a document, which compact caches do not store, see SetCompact. It
+// returns false if the document is gone.
+type Resolver func(docID string, results []search.SearchResult) bool
+
+// SetCompact makes the cache store results without the sentences,
+// sources and record values they share with their document, only keeping
+// their sentence indices. resolve fills them in again when entries are
+// read. It must be called before the cache is shared.
+func (c *SearchCache) SetCompact(resolve Resolver) {
+	c.resolve = resolve
+}
+
+// compact returns a copy of results without the data resolve restores
+func (c *SearchCache) compact(results []search.SearchResult) []search.SearchResult {
+	if c.resolve == nil {
+		return results
+	}
+	out := make([]search.SearchResult, len(results))
+	for i, r := range results {
+		out[i] = search.SearchResult{
+			SentenceIndex: r.SentenceIndex,
+			Index:         r.Index,
+			Match:         r.Match,
+			Distance:      r.Distance,
+			Expansion:     r.Expansion,
+		}
+	}
+	return out
+}
+
+// expand returns a copy of compact results with the data resolve restores
+func (c *SearchCache) expand(docID string, results []search.SearchResult) ([]search.SearchResult, bool) {
+	if c.resolve == nil {
+		return results, true
+	}
+	out := make([]search.SearchResult, len(results))
+	copy(out, results)
+	return out, c.resolve(docID, out)
+}
+
+// entrySize returns the bytes an entry keeps alive: its map slot, key,
+// eviction bookkeeping, results and candidates. Strings shared with the
+// document count too, as they outlive it while the entry is cached.
+func entrySize(key CacheKey, results []search.SearchResult, candidates *search.Candidates) int {
+	size := entryOverhead + mapSlotSize + mapEntryOverhead
+	size += len(key.DocID) + len(key.Query)
+	size += resultsSize(results)
+	if candidates != nil {
+		size += candidatesSize + len(candidates.Query) + cap(candidates.Indices)*intSize
+	}
+	return size
+}
+
+// resultsSize returns the bytes of results, including the strings and
+// record values they reference
+func resultsSize(results []search.SearchResult) int {
+	size := cap(results) * resultSize
+	for _, r := range results {
+		size += len(r.Sentence) + len(r.Match) + len(r.Expansion)
+		size += len(r.Heading) + len(r.Field) + len(r.ChapterTitle)
+		if r.Values != nil {
+			size += valueSize(r.Values)
+		}
+	}
+	return size
+}
+
+// valueSize returns the bytes referenced by a decoded record value.
+// Strings held in interfaces have their header boxed on the heap.
+func valueSize(v any) int {
+	switch v := v.(type) {
+	case string:
+		return stringSize + len(v)
+	case json.Number:
+		return stringSize + len(v)
+	case map[string]any:
+		size := mapSize(len(v), stringSize+ifaceSize)
+		for k, e := range v {
+			size += len(k) + valueSize(e)
+		}
+		return size
+	case []any:
+		size := cap(v) * ifaceSize
+		for _, e := range v {
+			size += valueSize(e)
+		}
+		return size
+	}
+	return 0
+}
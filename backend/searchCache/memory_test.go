@@ -0,0 +1,161 @@
+package searchCache
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/swanckel93/fuzzy_api/models"
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+func TestValueSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  int
+	}{
+		{"nil", nil, 0},
+		{"string", "abc", stringSize + 3},
+		{"number", json.Number("9.99"), stringSize + 4},
+		{"bool", true, 0},
+		{"list", []any{"a", "bc"}, 2*ifaceSize + 2*stringSize + 3},
+		{"map", map[string]any{"k": "v"}, mapHeaderSize + 8*(stringSize+ifaceSize+1) + 1 + stringSize + 1},
+		{"empty", map[string]any{}, mapHeaderSize},
+		{"nested", map[string]any{"k": []any{}}, mapHeaderSize + 8*(stringSize+ifaceSize+1) + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := valueSize(tt.value); got != tt.want {
+				t.Errorf("Test %q failed. Got: %d, want %d", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntrySize(t *testing.T) {
+	key := CacheKey{DocID: "doc", Query: "inv"}
+	base := entrySize(key, nil, nil)
+	if want := entryOverhead + mapSlotSize + mapEntryOverhead + len("doc") + len("inv"); base != want {
+		t.Errorf("Test %q failed. Got: %d, want %d", "empty", base, want)
+	}
+
+	r := []search.SearchResult{{Sentence: "the invoice", Match: "inv", Source: models.Source{Heading: "Bills"}, Values: map[string]any{}}}
+	if got, want := entrySize(key, r, nil), base+resultSize+len("the invoice")+len("inv")+len("Bills")+mapHeaderSize; got != want {
+		t.Errorf("Test %q failed. Got: %d, want %d", "results", got, want)
+	}
+
+	// Spare capacity is allocated as well
+	if got, want := entrySize(key, make([]search.SearchResult, 0, 4), nil), base+4*resultSize; got != want {
+		t.Errorf("Test %q failed. Got: %d, want %d", "capacity", got, want)
+	}
+
+	cand := &search.Candidates{Query: "inv", Indices: make([]int, 2, 8)}
+	if got, want := entrySize(key, nil, cand), base+candidatesSize+len("inv")+8*intSize; got != want {
+		t.Errorf("Test %q failed. Got: %d, want %d", "candidates", got, want)
+	}
+}
+
+func TestCompactSearchCache(t *testing.T) {
+	sentences := []string{"first sentence", "second sentence"}
+	resolved := 0
+	resolve := func(docID string, results []search.SearchResult) bool {
+		if docID != "doc" {
+			return false
+		}
+		resolved++
+		for i := range results {
+			results[i].Sentence = sentences[results[i].SentenceIndex]
+			results[i].Heading = "Intro"
+		}
+		return true
+	}
+
+	full := []search.SearchResult{{Sentence: sentences[1], SentenceIndex: 1, Match: "second", Source: models.Source{Heading: "Intro"}, Values: map[string]any{"a": "b"}}}
+	c := NewSearchCache(1)
+	c.SetCompact(resolve)
+	c.Set("doc", "second", full)
+	c.Set("gone", "second", full)
+
+	key := CacheKey{DocID: "doc", Query: "second"}
+	stored, _ := c.shardFor(key).get(key, c.now())
+	if stored[0].Sentence != "" || stored[0].Values != nil || stored[0].Match != "second" {
+		t.Errorf("Test %q failed. Stored: %+v", "compact", stored[0])
+	}
+	if c.Size() >= 2*entrySize(key, full, nil) {
+		t.Errorf("Test %q failed. Size %d counts the sentences", "size", c.Size())
+	}
+
+	got, ok := c.Get("doc", "second")
+	if !ok || got[0].Sentence != sentences[1] || got[0].Heading != "Intro" || resolved != 1 {
+		t.Errorf("Test %q failed. Got: %+v", "resolve", got)
+	}
+	// Callers own the results they get
+	got[0].Sentence = "changed"
+	if again, _ := c.Get("doc", "second"); again[0].Sentence != sentences[1] {
+		t.Errorf("Test %q failed. Got: %+v", "copy", again)
+	}
+
+	if _, ok := c.Get("gone", "second"); ok {
+		t.Errorf("Test %q failed. Results of a missing document were returned", "unresolved")
+	}
+	if st := c.Stats(); st.Hits != 3 || st.Misses != 1 {
+		t.Errorf("Test %q failed. Got: %+v", "unresolved miss", st)
+	}
+}
+
+// TestEntrySizeHeap checks the estimated size of many entries against the
+// heap they actually take up
+func TestEntrySizeHeap(t *testing.T) {
+	const entries = 20000
+	tests := []struct {
+		name   string
+		result func(i int) search.SearchResult
+	}{
+		{"sentences", func(i int) search.SearchResult {
+			return search.SearchResult{Sentence: fmt.Sprintf("sentence %d of a document about invoices", i), Match: "invoice"}
+		}},
+		{"records", func(i int) search.SearchResult {
+			return search.SearchResult{
+				Sentence: fmt.Sprint("Widget ", i),
+				Source:   models.Source{Heading: fmt.Sprint("products[", i, "]")},
+				Values:   map[string]any{"name": fmt.Sprint("Widget ", i), "price": json.Number(fmt.Sprint(i, ".99")), "tags": []any{"a", "b"}},
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, policy := range Policies() {
+			t.Run(tt.name+"/"+policy, func(t *testing.T) {
+				c := NewSearchCache(1024)
+				if err := c.SetPolicy(policy); err != nil {
+					t.Fatal(err)
+				}
+				before := heapAlloc()
+				for i := range entries {
+					results := make([]search.SearchResult, 3)
+					for j := range results {
+						results[j] = tt.result(i*len(results) + j)
+					}
+					c.Set(fmt.Sprint("doc", i%10), fmt.Sprint("query ", i), results)
+				}
+				heap := heapAlloc() - before
+				runtime.KeepAlive(c)
+
+				if ratio := float64(c.Size()) / float64(heap); ratio < 0.8 || ratio > 1.25 {
+					t.Errorf("Test %q failed. Estimated %d bytes, heap grew by %d", tt.name, c.Size(), heap)
+				}
+			})
+		}
+	}
+}
+
+// heapAlloc returns the bytes of live heap objects
+func heapAlloc() int {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return int(m.HeapAlloc)
+}
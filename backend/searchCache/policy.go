@@ -0,0 +1,135 @@
+package searchCache
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// Eviction policy names, in the order they are listed by Policies
+const (
+	PolicyLRU     = "lru"
+	PolicyLFU     = "lfu"
+	PolicyTinyLFU = "tinylfu"
+)
+
+// DefaultPolicy is the eviction policy of caches created by NewSearchCache
+const DefaultPolicy = PolicyLRU
+
+// Policy decides which entries a shard evicts to stay within its size
+// budget. Shards call it with their lock held, so implementations need no
+// locking of their own.
+type Policy interface {
+	// Access records a lookup of key, whether it is cached or not
+	Access(key CacheKey)
+	// Add records that an entry of size bytes was stored for key and
+	// returns the keys to evict so that the entries fit into the budget.
+	// They may include key itself if the policy does not admit it.
+	Add(key CacheKey, size int) []CacheKey
+	// Remove forgets key, which was removed for another reason than
+	// eviction, e.g. because it expired or its document was replaced
+	Remove(key CacheKey)
+	// Keys returns the cached keys, the next one to be evicted first
+	Keys() []CacheKey
+}
+
+// NewPolicy creates a policy for a shard with a budget of maxSize bytes
+type NewPolicy func(maxSize int) Policy
+
+var policies = map[string]NewPolicy{
+	PolicyLRU:     newLRU,
+	PolicyLFU:     newLFU,
+	PolicyTinyLFU: newTinyLFU,
+}
+
+// Policies returns the names of the available eviction policies
+func Policies() []string {
+	return []string{PolicyLRU, PolicyLFU, PolicyTinyLFU}
+}
+
+// SetPolicy sets the eviction policy by name, see Policies. It must be
+// called before the cache is shared.
+func (c *SearchCache) SetPolicy(name string) error {
+	newPolicy, ok := policies[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown cache eviction policy %q, expected one of: %s",
+			name, strings.Join(Policies(), ", "))
+	}
+	c.SetPolicyFunc(strings.ToLower(name), newPolicy)
+	return nil
+}
+
+// SetPolicyFunc sets an eviction policy that is not built in; name is
+// reported by Stats. Entries already cached are handed to the new policy,
+// the ones it evicts first first. It must be called before the cache is
+// shared.
+func (c *SearchCache) SetPolicyFunc(name string, newPolicy NewPolicy) {
+	c.policy, c.newPolicy = name, newPolicy
+	for _, s := range c.shards {
+		s.mu.Lock()
+		old := s.policy
+		s.policy = newPolicy(s.maxSize)
+		for _, key := range old.Keys() {
+			s.drop(s.policy.Add(key, s.data[key].size), key)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// node is the bookkeeping of a policy for an entry
+type node struct {
+	key    CacheKey
+	size   int
+	freq   int    // accesses since the entry was added, for LFU
+	tick   uint64 // time of the last access, for LFU
+	index  int    // position in the heap, for LFU
+	region region // for W-TinyLFU
+}
+
+// lru evicts the least recently used entries
+type lru struct {
+	maxSize, size int
+	order         *list.List // elements hold *node, most recent at the back
+	elems         map[CacheKey]*list.Element
+}
+
+func newLRU(maxSize int) Policy {
+	return &lru{maxSize: maxSize, order: list.New(), elems: make(map[CacheKey]*list.Element)}
+}
+
+func (p *lru) Access(key CacheKey) {
+	if elem, ok := p.elems[key]; ok {
+		p.order.MoveToBack(elem)
+	}
+}
+
+func (p *lru) Add(key CacheKey, size int) []CacheKey {
+	p.elems[key] = p.order.PushBack(&node{key: key, size: size})
+	p.size += size
+	var evicted []CacheKey
+	for p.size > p.maxSize {
+		n := p.order.Front().Value.(*node)
+		p.Remove(n.key)
+		evicted = append(evicted, n.key)
+	}
+	return evicted
+}
+
+func (p *lru) Remove(key CacheKey) {
+	if elem, ok := p.elems[key]; ok {
+		p.size -= p.order.Remove(elem).(*node).size
+		delete(p.elems, key)
+	}
+}
+
+func (p *lru) Keys() []CacheKey {
+	return listKeys(nil, p.order)
+}
+
+// listKeys appends the keys of the nodes of l, front first
+func listKeys(keys []CacheKey, l *list.List) []CacheKey {
+	for e := l.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*node).key)
+	}
+	return keys
+}
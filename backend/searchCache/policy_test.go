@@ -0,0 +1,178 @@
+package searchCache
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetPolicy(t *testing.T) {
+	c := testCache(3, 10)
+	for _, q := range []string{"a", "b", "c"} {
+		c.Set("doc", q, results(1, 10))
+	}
+	if err := c.SetPolicy("TinyLFU"); err != nil {
+		t.Fatalf("Test %q failed. Error: %v", "set", err)
+	}
+	if st := c.Stats(); st.Policy != PolicyTinyLFU || st.Entries != 3 {
+		t.Errorf("Test %q failed. Entries were not handed over: %+v", "set", st)
+	}
+	if err := c.SetPolicy("fifo"); err == nil || !strings.Contains(err.Error(), "lru, lfu, tinylfu") {
+		t.Errorf("Test %q failed. Got: %v", "unknown", err)
+	}
+}
+
+// TestPolicies checks that every policy keeps the cache within its budget
+// and tracks exactly the cached entries
+func TestPolicies(t *testing.T) {
+	for _, name := range Policies() {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			c := testCache(20, 10)
+			if err := c.SetPolicy(name); err != nil {
+				t.Fatal(err)
+			}
+			s := c.shards[0]
+			for i := range 5000 {
+				doc, q := fmt.Sprint("doc", rng.Intn(3)), fmt.Sprint(rng.Intn(100))
+				switch op := rng.Intn(20); {
+				case op == 0:
+					c.Invalidate(doc)
+				case op < 10:
+					c.Get(doc, q)
+				default:
+					c.Set(doc, q, results(1+rng.Intn(3), 10))
+				}
+
+				keys := s.policy.Keys()
+				if len(keys) != len(s.data) || c.Size() > s.maxSize {
+					t.Fatalf("Test %q failed. Step %d: %d keys, %d entries, %d bytes", name, i, len(keys), len(s.data), c.Size())
+				}
+				for _, key := range keys {
+					if _, ok := s.data[key]; !ok {
+						t.Fatalf("Test %q failed. Step %d: %v is not cached", name, i, key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLFU(t *testing.T) {
+	c := testCache(3, 10)
+	c.SetPolicy(PolicyLFU)
+	for _, q := range []string{"a", "b", "c"} {
+		c.Set("doc", q, results(1, 10))
+	}
+	c.Get("doc", "a")
+	c.Get("doc", "a")
+	c.Get("doc", "b")
+
+	// Entries that were never looked up go first, the oldest of them first
+	c.Set("doc", "d", results(1, 10))
+	c.Set("doc", "e", results(1, 10))
+	var got []string
+	for _, key := range c.shards[0].policy.Keys() {
+		got = append(got, key.Query)
+	}
+	if want := []string{"e", "b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("Test %q failed. Got: %v, want %v", "lfu", got, want)
+	}
+}
+
+func TestTinyLFU(t *testing.T) {
+	c := testCache(100, 10)
+	c.SetPolicy(PolicyTinyLFU)
+	for range 3 {
+		for i := range 50 {
+			if _, ok := c.Get("doc", fmt.Sprint("popular", i)); !ok {
+				c.Set("doc", fmt.Sprint("popular", i), results(1, 10))
+			}
+		}
+	}
+	// A scan of unique queries that would fill the cache many times over
+	for i := range 1000 {
+		c.Get("doc", fmt.Sprint("scan", i))
+		c.Set("doc", fmt.Sprint("scan", i), results(1, 10))
+	}
+	kept := 0
+	for i := range 50 {
+		if _, ok := c.Get("doc", fmt.Sprint("popular", i)); ok {
+			kept++
+		}
+	}
+	if st := c.Stats(); kept < 45 || st.Rejections == 0 {
+		t.Errorf("Test %q failed. Kept %d popular entries: %+v", "scan", kept, st)
+	}
+}
+
+func TestSketch(t *testing.T) {
+	s := newSketch(minSketchWidth)
+	key := CacheKey{DocID: "doc", Query: "q"}
+	for range 5 {
+		s.add(key)
+	}
+	if n := s.estimate(key); n != 5 {
+		t.Errorf("Test %q failed. Got: %d", "estimate", n)
+	}
+	for range maxCount {
+		s.add(key)
+	}
+	if n := s.estimate(key); n != maxCount {
+		t.Errorf("Test %q failed. Got: %d", "saturated", n)
+	}
+
+	// Counts halve after a sample of additions
+	for i := range 10*s.width() - 20 {
+		s.add(CacheKey{DocID: "doc", Query: fmt.Sprint(i)})
+	}
+	if n := s.estimate(key); n > maxCount/2+1 {
+		t.Errorf("Test %q failed. Got: %d", "aging", n)
+	}
+}
+
+func TestTinyLFUGrowth(t *testing.T) {
+	p := newTinyLFU(100).(*tinyLFU)
+	hot := CacheKey{DocID: "doc", Query: "hot"}
+	for range maxCount {
+		p.Access(hot)
+	}
+	add := func(key CacheKey) {
+		p.Access(key)
+		p.Add(key, 1)
+	}
+	// Enough entries to widen the sketch
+	for i := range 100 {
+		add(CacheKey{DocID: "doc", Query: fmt.Sprint("cold", i)})
+	}
+	if p.sketch.width() <= minSketchWidth {
+		t.Fatalf("Test %q failed. Sketch width: %d", "growth", p.sketch.width())
+	}
+	// Cached entries are looked up again, so hot only wins by its earlier lookups
+	for i := range 100 {
+		p.sketch.add(CacheKey{DocID: "doc", Query: fmt.Sprint("cold", i)})
+	}
+
+	// Counts only overestimate, so hot keeps at least half of its lookups
+	if n := p.sketch.estimate(hot); n < maxCount/2 {
+		t.Fatalf("Test %q failed. Estimate after growth: %d", "growth", n)
+	}
+
+	// Lookups before the growth still count when the key leaves the window
+	add(hot)
+	for i := range 5 {
+		add(CacheKey{DocID: "doc", Query: fmt.Sprint("late", i)})
+	}
+	if _, ok := p.elems[hot]; ok {
+		return
+	}
+	// Unless a cached key shares all counters with hot
+	for key := range p.elems {
+		if p.sketch.estimate(key) >= p.sketch.estimate(hot) {
+			return
+		}
+	}
+	t.Errorf("Test %q failed. Frequent key was not admitted", "growth")
+}
@@ -1,10 +1,16 @@
 package searchCache
 
 import (
+	"hash/maphash"
 	"sync"
+	"time"
+
 	"github.com/swanckel93/fuzzy_api/search" // adjust import according to your project structure
 )
 
+// DefaultShards is the number of shards of caches created by NewSearchCache
+const DefaultShards = 16
+
 // CacheKey is a tuple of Document ID and Query used as a key in the cache
 type CacheKey struct {
 	DocID string
@@ -13,101 +19,349 @@ type CacheKey struct {
 
 // cacheEntry holds the actual data in the cache
 type cacheEntry struct {
-	key    CacheKey
-	value  []search.SearchResult
-	size   int // in bytes
+	key        CacheKey
+	value      []search.SearchResult
+	candidates *search.Candidates // to refine searches for longer queries, may be nil
+	size       int                // in bytes
+	expires    time.Time          // zero if the entry does not expire
 }
 
+// Stats describes the use of a cache since it was created
+type Stats struct {
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	HitRate     float64 `json:"hit_rate"`    // hits per lookup, 0 without lookups
+	Coalesced   uint64  `json:"coalesced"`   // misses that waited for an identical search in flight
+	Evictions   uint64  `json:"evictions"`   // entries removed to make room for others
+	Rejections  uint64  `json:"rejections"`  // new entries the eviction policy did not admit
+	Expirations uint64  `json:"expirations"` // entries removed after their TTL
+	Entries     int     `json:"entries"`
+	Bytes       int     `json:"bytes"`     // estimated size of the entries, including pending ones
+	MaxBytes    int     `json:"max_bytes"` // size budget
+	Shards      int     `json:"shards"`
+	Policy      string  `json:"policy"`        // eviction policy, see Policies
+	Pending     int     `json:"pending"`       // loaded entries waiting for their document to be uploaded again
+	TTL         string  `json:"ttl,omitempty"` // default TTL of entries, empty if they do not expire
+}
+
+// SearchCache is a cache of search results bounded by their size, which
+// evicts entries by an eviction policy, LRU unless SetPolicy chooses
+// another. Keys are spread over shards by their hash; every shard has its
+// own lock, policy and an equal part of the size budget, so that
+// concurrent searches rarely wait for each other.
 type SearchCache struct {
+	seed      maphash.Seed
+	shards    []*shard
+	ttl       time.Duration    // default TTL, 0 for none
+	now       func() time.Time // replaced in tests
+	policy    string           // name of the eviction policy
+	newPolicy NewPolicy
+
+	resolve Resolver // set for compact caches, see SetCompact
+	flights flights  // searches in flight, see Do
+	pending pending  // loaded entries waiting for their document, see Load
+}
+
+// shard is an independently locked cache. Its policy tracks the entries
+// and chooses the ones to evict.
+type shard struct {
 	mu          sync.Mutex
 	data        map[CacheKey]*cacheEntry
-	order       []CacheKey
+	policy      Policy
 	currentSize int
 	maxSize     int // in bytes
+
+	hits, misses, evictions, rejections, expirations uint64
 }
 
 // NewSearchCache creates a new search cache with a given max size in MB
 func NewSearchCache(maxSizeMB int) *SearchCache {
-	return &SearchCache{
-		data:    make(map[CacheKey]*cacheEntry),
-		order:   []CacheKey{},
-		maxSize: maxSizeMB * 1024 * 1024,
+	return NewShardedSearchCache(maxSizeMB, DefaultShards)
+}
+
+// NewShardedSearchCache creates a search cache with a given max size in MB,
+// split into the given number of shards. A single entry must fit into the
+// budget of one shard.
+func NewShardedSearchCache(maxSizeMB, shards int) *SearchCache {
+	return newSearchCache(maxSizeMB*1024*1024, shards)
+}
+
+// newSearchCache creates a cache of maxSize bytes, which are divided among
+// the shards so that their budgets sum up to maxSize
+func newSearchCache(maxSize, shards int) *SearchCache {
+	shards = max(shards, 1)
+	c := &SearchCache{
+		seed:      maphash.MakeSeed(),
+		shards:    make([]*shard, shards),
+		now:       time.Now,
+		policy:    DefaultPolicy,
+		newPolicy: policies[DefaultPolicy],
+	}
+	for i := range c.shards {
+		size := maxSize / shards
+		if i < maxSize%shards {
+			size++
+		}
+		c.shards[i] = &shard{
+			data:    make(map[CacheKey]*cacheEntry),
+			policy:  c.newPolicy(size),
+			maxSize: size,
+		}
+	}
+	return c
+}
+
+// shardFor returns the shard holding key
+func (c *SearchCache) shardFor(key CacheKey) *shard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
 	}
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// SetTTL sets the time after which entries stored by Set expire, 0 to keep
+// them until they are evicted. It must be called before the cache is
+// shared; entries already cached are not affected.
+func (c *SearchCache) SetTTL(ttl time.Duration) {
+	c.ttl = ttl
 }
 
 // Get retrieves search results from the cache by document ID and query
 func (c *SearchCache) Get(docID, query string) ([]search.SearchResult, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	key := CacheKey{DocID: docID, Query: query}
-	if entry, ok := c.data[key]; ok {
-		// Move to the end (most recent)
-		c.moveToEnd(key)
-		return entry.value, true
+	s := c.shardFor(key)
+	results, ok := s.get(key, c.now())
+	if !ok {
+		return nil, false
+	}
+	// Documents are never removed, only replaced, which invalidates
+	// their entries, so resolving fails only in a race with a replacement
+	results, ok = c.expand(docID, results)
+	if !ok {
+		s.unhit()
+		return nil, false
 	}
-	return nil, false
+	return results, true
 }
 
-// Set adds search results to the cache, evicting old entries if necessary
+// Set adds search results to the cache, evicting old entries if necessary.
+// The entry expires after the TTL set by SetTTL, if any.
 func (c *SearchCache) Set(docID, query string, results []search.SearchResult) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.SetWithTTL(docID, query, results, c.ttl)
+}
 
+// SetWithTTL is Set for an entry that expires after ttl, or never if ttl is 0
+func (c *SearchCache) SetWithTTL(docID, query string, results []search.SearchResult, ttl time.Duration) {
 	key := CacheKey{DocID: docID, Query: query}
+	c.shardFor(key).set(key, c.compact(results), nil, c.expiry(ttl))
+}
 
-	size := estimateSize(results)
-	if size > c.maxSize {
-		return // entry too big to cache
+// expiry returns when an entry stored now expires after ttl, zero if ttl is 0
+func (c *SearchCache) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
 	}
+	return c.now().Add(ttl)
+}
 
-	// If already exists, remove and update
-	if old, ok := c.data[key]; ok {
-		c.remove(key, old.size)
+// Candidates returns the candidates cached with the results of a search,
+// see search.RefineSearch. It does not count as a lookup and does not make
+// the entry more recent for the eviction policy.
+func (c *SearchCache) Candidates(docID, query string) (*search.Candidates, bool) {
+	key := CacheKey{DocID: docID, Query: query}
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.candidates == nil || entry.expired(c.now()) {
+		return nil, false
+	}
+	return entry.candidates, true
+}
+
+// Invalidate removes the entries of a document, e.g. after it was replaced,
+// and returns how many there were
+func (c *SearchCache) Invalidate(docID string) int {
+	c.flights.invalidate(docID)
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, entry := range s.data {
+			if key.DocID == docID {
+				s.remove(entry)
+				n++
+			}
+		}
+		s.mu.Unlock()
 	}
+	return n
+}
 
-	// Evict if needed
-	for c.currentSize+size > c.maxSize && len(c.order) > 0 {
-		oldestKey := c.order[0]
-		c.remove(oldestKey, c.data[oldestKey].size)
+// Clear removes all entries, including loaded ones that are not restored
+// yet, and returns how many there were. Statistics are kept.
+func (c *SearchCache) Clear() int {
+	c.flights.clear()
+	c.pending.mu.Lock()
+	n := 0
+	for _, entries := range c.pending.entries {
+		n += len(entries)
 	}
+	c.pending.entries = nil
+	c.pending.size = 0
+	c.pending.mu.Unlock()
 
-	// Insert new entry
-	c.data[key] = &cacheEntry{key: key, value: results, size: size}
-	c.order = append(c.order, key)
-	c.currentSize += size
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += len(s.data)
+		s.data = make(map[CacheKey]*cacheEntry)
+		s.policy = c.newPolicy(s.maxSize)
+		s.currentSize = 0
+		s.mu.Unlock()
+	}
+	return n
 }
 
-// moveToEnd moves the given key to the end of the access order slice (most recent)
-func (c *SearchCache) moveToEnd(key CacheKey) {
-	for i, k := range c.order {
-		if k == key {
-			// Move to end
-			c.order = append(c.order[:i], c.order[i+1:]...)
-			c.order = append(c.order, key)
-			return
-		}
+// Stats returns the counters and size of the cache
+func (c *SearchCache) Stats() Stats {
+	st := Stats{Shards: len(c.shards), Policy: c.policy}
+	if c.ttl > 0 {
+		st.TTL = c.ttl.String()
+	}
+	for _, s := range c.shards {
+		s.mu.Lock()
+		st.Hits += s.hits
+		st.Misses += s.misses
+		st.Evictions += s.evictions
+		st.Rejections += s.rejections
+		st.Expirations += s.expirations
+		st.Entries += len(s.data)
+		st.Bytes += s.currentSize
+		st.MaxBytes += s.maxSize
+		s.mu.Unlock()
+	}
+	c.flights.mu.Lock()
+	st.Coalesced = c.flights.coalesced
+	c.flights.mu.Unlock()
+	c.pending.mu.Lock()
+	for _, entries := range c.pending.entries {
+		st.Pending += len(entries)
 	}
+	st.Bytes += c.pending.size
+	c.pending.mu.Unlock()
+	if lookups := st.Hits + st.Misses; lookups > 0 {
+		st.HitRate = float64(st.Hits) / float64(lookups)
+	}
+	return st
 }
 
-// remove removes the key and updates the current size
-func (c *SearchCache) remove(key CacheKey, size int) {
-	delete(c.data, key)
-	for i, k := range c.order {
-		if k == key {
-			c.order = append(c.order[:i], c.order[i+1:]...)
-			break
-		}
+// Len returns the number of cached entries
+func (c *SearchCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += len(s.data)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Size returns the estimated size of the cached entries in bytes
+func (c *SearchCache) Size() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.currentSize
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// maxSize returns the size budget of the cache in bytes
+func (c *SearchCache) maxSize() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.maxSize
+	}
+	return n
+}
+
+func (s *shard) get(key CacheKey, now time.Time) ([]search.SearchResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data[key]
+	if ok && entry.expired(now) {
+		s.remove(entry)
+		s.expirations++
+		ok = false
 	}
-	c.currentSize -= size
+	// Misses count too, policies may admit entries by how often they
+	// were looked up
+	s.policy.Access(key)
+	if !ok {
+		s.misses++
+		return nil, false
+	}
+	s.hits++
+	return entry.value, true
+}
+
+// unhit counts a lookup that get counted as a hit as a miss, because its
+// results could not be used
+func (s *shard) unhit() {
+	s.mu.Lock()
+	s.hits--
+	s.misses++
+	s.mu.Unlock()
 }
 
-// estimateSize estimates the size of the search results in bytes
-func estimateSize(results []search.SearchResult) int {
-	size := 0
-	for _, r := range results {
-		// Approximate size: 16 bytes overhead + string lengths + ints
-		size += 16 + len(r.Sentence) + len(r.Match) + 4*8 // 8 bytes for each int field
+func (s *shard) set(key CacheKey, results []search.SearchResult, candidates *search.Candidates, expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := entrySize(key, results, candidates)
+	if size > s.maxSize {
+		return // entry too big to cache
 	}
-	return size
+
+	// If already exists, remove and update
+	if old, ok := s.data[key]; ok {
+		s.remove(old)
+	}
+
+	// Insert new entry, evicting the ones the policy chooses
+	s.data[key] = &cacheEntry{key: key, value: results, candidates: candidates, size: size, expires: expires}
+	s.currentSize += size
+	s.drop(s.policy.Add(key, size), key)
+}
+
+// drop removes the entries the policy evicted. Evicting added, the entry
+// just stored, means the policy did not admit it.
+func (s *shard) drop(evicted []CacheKey, added CacheKey) {
+	for _, key := range evicted {
+		entry := s.data[key]
+		delete(s.data, key)
+		s.currentSize -= entry.size
+		if key == added {
+			s.rejections++
+		} else {
+			s.evictions++
+		}
+	}
+}
+
+// expired reports whether the entry's TTL has passed at now
+func (e *cacheEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// remove removes an entry for another reason than eviction
+func (s *shard) remove(entry *cacheEntry) {
+	delete(s.data, entry.key)
+	s.currentSize -= entry.size
+	s.policy.Remove(entry.key)
 }
@@ -0,0 +1,320 @@
+package searchCache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+// results returns n results whose sentences are size bytes long
+func results(n, size int) []search.SearchResult {
+	out := make([]search.SearchResult, n)
+	for i := range out {
+		out[i] = search.SearchResult{Sentence: strings.Repeat("x", size), SentenceIndex: i}
+	}
+	return out
+}
+
+// oneResult returns the size of an entry of doc for a one letter query
+// with one result whose sentence is size bytes long
+func oneResult(size int) int {
+	return entrySize(CacheKey{DocID: "doc", Query: "a"}, results(1, size), nil)
+}
+
+// testCache returns a single shard cache that fits exactly entries of one
+// result with a sentence of sentenceSize bytes, for one letter queries
+func testCache(entries, sentenceSize int) *SearchCache {
+	return newSearchCache(entries*oneResult(sentenceSize), 1)
+}
+
+func TestSearchCache(t *testing.T) {
+	type op struct {
+		set   bool
+		query string
+	}
+	tests := []struct {
+		name string
+		ops  []op
+		want []string // queries still cached, in LRU order
+	}{
+		{"fills up", []op{{true, "a"}, {true, "b"}, {true, "c"}}, []string{"a", "b", "c"}},
+		{"evicts oldest", []op{{true, "a"}, {true, "b"}, {true, "c"}, {true, "d"}}, []string{"b", "c", "d"}},
+		{"get refreshes", []op{{true, "a"}, {true, "b"}, {true, "c"}, {false, "a"}, {true, "d"}}, []string{"c", "a", "d"}},
+		{"set replaces", []op{{true, "a"}, {true, "b"}, {true, "a"}, {true, "c"}, {true, "d"}}, []string{"a", "c", "d"}},
+		{"miss changes nothing", []op{{true, "a"}, {false, "x"}, {true, "b"}}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCache(3, 10)
+			for _, o := range tt.ops {
+				if o.set {
+					c.Set("doc", o.query, results(1, 10))
+				} else {
+					c.Get("doc", o.query)
+				}
+			}
+
+			s := c.shards[0]
+			var got []string
+			for _, key := range s.policy.Keys() {
+				got = append(got, key.Query)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Test %q failed. Got: %v", tt.name, got)
+			}
+			if c.Len() != len(tt.want) || len(s.data) != len(tt.want) {
+				t.Errorf("Test %q failed. Got %d entries, %d keys", tt.name, c.Len(), len(s.data))
+			}
+			if c.Size() != len(tt.want)*oneResult(10) {
+				t.Errorf("Test %q failed. Got size: %d", tt.name, c.Size())
+			}
+		})
+	}
+}
+
+func TestSearchCacheSizes(t *testing.T) {
+	c := testCache(4, 10)
+
+	// An entry larger than the whole cache is not stored
+	c.Set("doc", "huge", results(10, 10))
+	if _, ok := c.Get("doc", "huge"); ok {
+		t.Errorf("Test %q failed. Oversized entry was cached", "oversized")
+	}
+
+	// A large entry evicts as many entries as needed
+	for _, q := range []string{"a", "b", "c", "d"} {
+		c.Set("doc", q, results(1, 10))
+	}
+	c.Set("doc", "big", results(4, 10))
+	for q, want := range map[string]bool{"a": false, "b": false, "c": false, "d": true, "big": true} {
+		if _, ok := c.Get("doc", q); ok != want {
+			t.Errorf("Test %q failed. Cached: %v", q, ok)
+		}
+	}
+	if c.Size() > c.shards[0].maxSize {
+		t.Errorf("Test %q failed. Size %d exceeds %d", "budget", c.Size(), c.shards[0].maxSize)
+	}
+
+	// Keys differ by document
+	c = testCache(4, 20)
+	c.Set("doc", "d", results(1, 10))
+	c.Set("other", "d", results(1, 20))
+	if got, _ := c.Get("doc", "d"); len(got[0].Sentence) != 10 {
+		t.Errorf("Test %q failed. Got: %+v", "documents", got)
+	}
+}
+
+func TestShardedSearchCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int
+		shards  int
+	}{
+		{"even", 1600, 16},
+		{"remainder", 1000, 3},
+		{"single", 1000, 1},
+		{"no shards", 1000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSearchCache(tt.maxSize, tt.shards)
+			total := 0
+			for _, s := range c.shards {
+				total += s.maxSize
+			}
+			if total != tt.maxSize {
+				t.Errorf("Test %q failed. Shard budgets sum up to %d", tt.name, total)
+			}
+		})
+	}
+
+	// Keys are found again in their shard, whichever goroutine sets them
+	c := newSearchCache(1<<20, 8)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				q := fmt.Sprint(g, "-", i)
+				c.Set("doc", q, results(1, 10))
+				if _, ok := c.Get("doc", q); !ok {
+					t.Errorf("Test %q failed. %s not cached", "concurrent", q)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if c.Len() != 1600 {
+		t.Errorf("Test %q failed. Got %d entries", "concurrent", c.Len())
+	}
+	used := 0
+	for _, s := range c.shards {
+		if len(s.data) > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("Test %q failed. Keys use %d shards", "spread", used)
+	}
+}
+
+func TestSearchCacheTTL(t *testing.T) {
+	c := testCache(10, 10)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	c.SetTTL(time.Minute)
+
+	c.Set("doc", "default", results(1, 10))
+	c.SetWithTTL("doc", "short", results(1, 10), time.Second)
+	c.SetWithTTL("doc", "forever", results(1, 10), 0)
+
+	now = now.Add(30 * time.Second)
+	for q, want := range map[string]bool{"default": true, "short": false, "forever": true} {
+		if _, ok := c.Get("doc", q); ok != want {
+			t.Errorf("Test %q failed. Cached after 30s: %v", q, ok)
+		}
+	}
+	now = now.Add(time.Hour)
+	for q, want := range map[string]bool{"default": false, "forever": true} {
+		if _, ok := c.Get("doc", q); ok != want {
+			t.Errorf("Test %q failed. Cached after 1h: %v", q, ok)
+		}
+	}
+
+	st := c.Stats()
+	if st.Expirations != 2 || st.Entries != 1 || st.TTL != "1m0s" {
+		t.Errorf("Test %q failed. Got: %+v", "stats", st)
+	}
+}
+
+func TestSearchCacheStats(t *testing.T) {
+	// Document and queries have the length of those of oneResult
+	size := entrySize(CacheKey{DocID: "b", Query: "q1"}, results(1, 10), nil)
+	c := newSearchCache(3*size, 1)
+	c.Set("a", "q1", results(1, 10))
+	c.Set("a", "q2", results(1, 10))
+	c.Set("b", "q1", results(1, 10))
+	c.Set("b", "q2", results(1, 10)) // evicts a/q1
+	c.Get("a", "q1")
+	c.Get("a", "q2")
+	c.Get("b", "q2")
+	c.Get("b", "q3")
+
+	want := Stats{
+		Hits: 2, Misses: 2, HitRate: 0.5, Evictions: 1, Entries: 3,
+		Bytes: 3 * size, MaxBytes: 3 * size, Shards: 1, Policy: PolicyLRU,
+	}
+	if got := c.Stats(); got != want {
+		t.Errorf("Test %q failed. Got: %+v", "stats", got)
+	}
+
+	if n := c.Invalidate("b"); n != 2 {
+		t.Errorf("Test %q failed. Removed %d", "invalidate", n)
+	}
+	if _, ok := c.Get("a", "q2"); !ok {
+		t.Errorf("Test %q failed. Other document was removed", "invalidate")
+	}
+	if n := c.Clear(); n != 1 || c.Len() != 0 || c.Size() != 0 {
+		t.Errorf("Test %q failed. Removed %d, left %d", "clear", n, c.Len())
+	}
+	if got := c.Stats(); got.Hits != 3 {
+		t.Errorf("Test %q failed. Statistics were reset: %+v", "clear", got)
+	}
+}
+
+func TestSearchCacheCandidates(t *testing.T) {
+	c := testCache(10, 100)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	c.SetTTL(time.Minute)
+
+	cand := &search.Candidates{Query: "inv", Indices: []int{4, 2}, Threshold: 1}
+	c.Do("doc", "inv", func() ([]search.SearchResult, *search.Candidates, error) {
+		return results(1, 10), cand, nil
+	})
+	c.Set("doc", "plain", results(1, 10))
+
+	if got, ok := c.Candidates("doc", "inv"); !ok || got != cand {
+		t.Errorf("Test %q failed. Got: %+v", "stored", got)
+	}
+	if _, ok := c.Candidates("doc", "plain"); ok {
+		t.Errorf("Test %q failed. Found candidates", "without candidates")
+	}
+	want := entrySize(CacheKey{DocID: "doc", Query: "inv"}, results(1, 10), cand) +
+		entrySize(CacheKey{DocID: "doc", Query: "plain"}, results(1, 10), nil)
+	if c.Size() != want {
+		t.Errorf("Test %q failed. Got size: %d", "size", c.Size())
+	}
+	if st := c.Stats(); st.Hits+st.Misses != 1 {
+		t.Errorf("Test %q failed. Candidates were counted as lookups: %+v", "stats", st)
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := c.Candidates("doc", "inv"); ok {
+		t.Errorf("Test %q failed. Found expired candidates", "expired")
+	}
+}
+
+func BenchmarkSearchCacheGet(b *testing.B) {
+	for _, n := range []int{100, 10000, 100000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			c := testCache(n, 100)
+			for i := range n {
+				c.Set("doc", fmt.Sprint(i), results(1, 100))
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Get("doc", fmt.Sprint(i%n))
+			}
+		})
+	}
+}
+
+func BenchmarkSearchCacheSet(b *testing.B) {
+	for _, n := range []int{100, 10000, 100000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			// The cache is full, so every Set evicts an entry
+			c := testCache(n, 100)
+			value := results(1, 100)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Set("doc", fmt.Sprint(i), value)
+			}
+		})
+	}
+}
+
+func BenchmarkSearchCacheParallel(b *testing.B) {
+	for _, shards := range []int{1, DefaultShards} {
+		b.Run(fmt.Sprint(shards, "shards"), func(b *testing.B) {
+			const n = 10000
+			c := newSearchCache(2*n*oneResult(100), shards)
+			value := results(1, 100)
+			for i := range n {
+				c.Set("doc", fmt.Sprint(i), value)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					// Mostly hits, with a miss and a Set every eighth search
+					q := fmt.Sprint(i % n)
+					if i%8 == 0 {
+						q = fmt.Sprint(n + i)
+					}
+					if _, ok := c.Get("doc", q); !ok {
+						c.Set("doc", q, value)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package searchCache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+// snapshotVersion changes whenever the snapshot format does
+const snapshotVersion = 1
+
+// ErrSnapshotVersion is returned by Load for snapshots of another format
+var ErrSnapshotVersion = errors.New("unsupported cache snapshot version")
+
+func init() {
+	// Types of record values in SearchResult.Values
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register(json.Number(""))
+}
+
+// HashFunc returns the content hash of a stored document, see storage.DocumentHash
+type HashFunc func(docID string) (string, bool)
+
+type snapshotHeader struct {
+	Version int
+	Saved   time.Time
+}
+
+// snapshotEntry is a cache entry with the hash of the document it was
+// computed from
+type snapshotEntry struct {
+	DocID      string
+	DocHash    string
+	Query      string
+	Results    []search.SearchResult
+	Candidates *search.Candidates
+	Expires    time.Time
+	Carried    bool // saved while it waited for its document, see Load
+
+	size int // in bytes, while pending
+}
+
+// expired reports whether the entry's TTL has passed at now
+func (e *snapshotEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// pending holds loaded entries of documents that are not stored yet
+type pending struct {
+	mu      sync.Mutex
+	entries map[string][]*snapshotEntry // by document
+	size    int                         // in bytes
+}
+
+// add keeps an entry if it fits into the budget of maxSize bytes
+func (p *pending) add(e *snapshotEntry, maxSize int) bool {
+	e.size = entrySize(CacheKey{DocID: e.DocID, Query: e.Query}, e.Results, e.Candidates)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.size+e.size > maxSize {
+		return false
+	}
+	if p.entries == nil {
+		p.entries = make(map[string][]*snapshotEntry)
+	}
+	p.entries[e.DocID] = append(p.entries[e.DocID], e)
+	p.size += e.size
+	return true
+}
+
+// take removes and returns the entries of a document
+func (p *pending) take(docID string) []*snapshotEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entries := p.entries[docID]
+	for _, e := range entries {
+		p.size -= e.size
+	}
+	delete(p.entries, docID)
+	return entries
+}
+
+// Save writes the entries of the cache to w, the next to be evicted first,
+// with the hash of the document each was computed from. Entries of
+// documents hash does not know are skipped, but loaded entries that still
+// wait for their document are written again, once. It returns how many
+// entries were written.
+func (c *SearchCache) Save(w io.Writer, hash HashFunc) (int, error) {
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Saved: c.now()}); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	now := c.now()
+	for _, s := range c.shards {
+		// Entries are copied so that encoding does not hold the lock
+		s.mu.Lock()
+		entries := make([]*cacheEntry, 0, len(s.data))
+		for _, key := range s.policy.Keys() {
+			entries = append(entries, s.data[key])
+		}
+		s.mu.Unlock()
+
+		for _, e := range entries {
+			docHash, ok := hash(e.key.DocID)
+			if !ok || e.expired(now) {
+				continue
+			}
+			// Snapshots hold complete results, whether the cache is compact or not
+			results, ok := c.expand(e.key.DocID, e.value)
+			if !ok {
+				continue
+			}
+			err := enc.Encode(snapshotEntry{
+				DocID:      e.key.DocID,
+				DocHash:    docHash,
+				Query:      e.key.Query,
+				Results:    results,
+				Candidates: e.candidates,
+				Expires:    e.expires,
+			})
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+
+	c.pending.mu.Lock()
+	defer c.pending.mu.Unlock()
+	for _, entries := range c.pending.entries {
+		for _, e := range entries {
+			if e.expired(now) {
+				continue
+			}
+			carried := *e
+			carried.Carried = true
+			if err := enc.Encode(&carried); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Load reads entries written by Save. Entries of documents stored with the
+// same hash are cached right away, entries of documents that are not
+// stored yet are kept until Restore is called for them, as long as they
+// fit into the budget of the cache left by the cached entries, and entries
+// of documents that changed are discarded. Entries that already waited
+// for their document before the snapshot was saved and expired entries
+// are skipped.
+func (c *SearchCache) Load(r io.Reader, hash HashFunc) (restored, kept int, err error) {
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, 0, err
+	}
+	if header.Version != snapshotVersion {
+		return 0, 0, fmt.Errorf("%w: %d", ErrSnapshotVersion, header.Version)
+	}
+
+	now := c.now()
+	for {
+		e := &snapshotEntry{}
+		if err := dec.Decode(e); errors.Is(err, io.EOF) {
+			return restored, kept, nil
+		} else if err != nil {
+			return restored, kept, err
+		}
+		if e.expired(now) {
+			continue
+		}
+
+		docHash, ok := hash(e.DocID)
+		switch {
+		case !ok:
+			if !e.Carried && c.pending.add(e, c.maxSize()-c.Size()) {
+				kept++
+			}
+		case docHash == e.DocHash:
+			c.restore(e)
+			restored++
+		}
+	}
+}
+
+// Restore caches the loaded entries of a document that was stored with
+// the given hash since, see Load, and returns how many there were. Entries
+// computed from a different version of the document are discarded.
+func (c *SearchCache) Restore(docID, hash string) int {
+	entries := c.pending.take(docID)
+	n := 0
+	now := c.now()
+	for _, e := range entries {
+		if e.DocHash == hash && !e.expired(now) {
+			c.restore(e)
+			n++
+		}
+	}
+	return n
+}
+
+// restore caches a loaded entry
+func (c *SearchCache) restore(e *snapshotEntry) {
+	key := CacheKey{DocID: e.DocID, Query: e.Query}
+	c.shardFor(key).set(key, c.compact(e.Results), e.Candidates, e.Expires)
+}
+
+// SaveFile writes a snapshot to path, replacing it only once the snapshot
+// is complete
+func (c *SearchCache) SaveFile(path string, hash HashFunc) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := c.Save(tmp, hash)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp.Name(), path)
+}
+
+// LoadFile loads a snapshot written by SaveFile. A missing file is not an
+// error.
+func (c *SearchCache) LoadFile(path string, hash HashFunc) (restored, kept int, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	return c.Load(f, hash)
+}
@@ -0,0 +1,147 @@
+package searchCache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+// hashes returns a HashFunc knowing the given documents
+func hashes(docs map[string]string) HashFunc {
+	return func(docID string) (string, bool) {
+		h, ok := docs[docID]
+		return h, ok
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	record := results(1, 10)
+	record[0].Values = map[string]any{
+		"name":  "Widget",
+		"price": json.Number("9.99"),
+		"tags":  []any{"a", "b"},
+		"brand": map[string]any{"name": "ACME"},
+		"stock": nil,
+	}
+	cand := &search.Candidates{Query: "wid", Indices: []int{0}, Threshold: 2}
+
+	saved := NewSearchCache(1)
+	saved.Set("same", "q", results(2, 10))
+	saved.Do("same", "wid", func() ([]search.SearchResult, *search.Candidates, error) { return record, cand, nil })
+	saved.Set("changed", "q", results(1, 10))
+	saved.Set("later", "q", results(1, 10))
+	saved.Set("gone", "q", results(1, 10))
+	saved.SetWithTTL("same", "expired", results(1, 10), time.Nanosecond)
+
+	var buf bytes.Buffer
+	before := hashes(map[string]string{"same": "h1", "changed": "h2", "later": "h3"})
+	if n, err := saved.Save(&buf, before); err != nil || n != 4 {
+		t.Fatalf("Test %q failed. Saved %d entries: %v", "save", n, err)
+	}
+
+	snapshot := bytes.Clone(buf.Bytes())
+	loaded := NewSearchCache(1)
+	after := hashes(map[string]string{"same": "h1", "changed": "h2-new"})
+	restored, kept, err := loaded.Load(&buf, after)
+	if err != nil || restored != 2 || kept != 1 {
+		t.Fatalf("Test %q failed. Restored %d, kept %d: %v", "load", restored, kept, err)
+	}
+	if got, ok := loaded.Get("same", "wid"); !ok || !reflect.DeepEqual(got, record) {
+		t.Errorf("Test %q failed. Got: %+v", "values", got)
+	}
+	if got, ok := loaded.Candidates("same", "wid"); !ok || !reflect.DeepEqual(got, cand) {
+		t.Errorf("Test %q failed. Got: %+v", "candidates", got)
+	}
+	for _, doc := range []string{"changed", "later", "gone"} {
+		if _, ok := loaded.Get(doc, "q"); ok {
+			t.Errorf("Test %q failed. Entry was cached", doc)
+		}
+	}
+	if st := loaded.Stats(); st.Pending != 1 {
+		t.Errorf("Test %q failed. Got: %+v", "pending", st)
+	}
+
+	// Entries waiting for their document are saved again
+	buf.Reset()
+	if n, _ := loaded.Save(&buf, after); n != 3 {
+		t.Errorf("Test %q failed. Saved %d entries", "save pending", n)
+	}
+
+	// but only once
+	reloaded := NewSearchCache(1)
+	if restored, kept, _ := reloaded.Load(&buf, after); restored != 2 || kept != 0 {
+		t.Errorf("Test %q failed. Restored %d, kept %d", "pending twice", restored, kept)
+	}
+
+	if n := loaded.Restore("later", "h3"); n != 1 {
+		t.Errorf("Test %q failed. Restored %d entries", "restore", n)
+	}
+	if _, ok := loaded.Get("later", "q"); !ok {
+		t.Errorf("Test %q failed. Entry was not cached", "restore")
+	}
+	if st := loaded.Stats(); st.Pending != 0 || st.Bytes != loaded.Size() {
+		t.Errorf("Test %q failed. Got: %+v", "restored pending", st)
+	}
+
+	changed := NewSearchCache(1)
+	changed.Load(bytes.NewReader(snapshot), after)
+	if n := changed.Restore("later", "h3-new"); n != 0 {
+		t.Errorf("Test %q failed. Restored %d entries of a changed document", "restore changed", n)
+	}
+}
+
+// TestSnapshotPendingBudget checks that entries waiting for their document
+// count towards the budget of the cache
+func TestSnapshotPendingBudget(t *testing.T) {
+	saved := testCache(5, 10)
+	for _, q := range []string{"a", "b", "c", "d", "e"} {
+		saved.Set("doc", q, results(1, 10))
+	}
+	var buf bytes.Buffer
+	saved.Save(&buf, hashes(map[string]string{"doc": "h"}))
+
+	loaded := testCache(2, 10)
+	_, kept, err := loaded.Load(&buf, hashes(nil))
+	if err != nil || kept != 2 {
+		t.Fatalf("Test %q failed. Kept %d: %v", "budget", kept, err)
+	}
+	if st := loaded.Stats(); st.Pending != 2 || st.Bytes != 2*oneResult(10) {
+		t.Errorf("Test %q failed. Got: %+v", "bytes", st)
+	}
+	loaded.Clear()
+	if st := loaded.Stats(); st.Bytes != 0 {
+		t.Errorf("Test %q failed. Got: %+v", "clear", st)
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	known := hashes(map[string]string{"doc": "h"})
+
+	c := NewSearchCache(1)
+	if restored, kept, err := c.LoadFile(path, known); err != nil || restored+kept != 0 {
+		t.Errorf("Test %q failed. Got: %d, %d, %v", "missing file", restored, kept, err)
+	}
+
+	c.Set("doc", "q", results(1, 10))
+	if _, err := c.SaveFile(path, known); err != nil {
+		t.Fatalf("Test %q failed. Error: %v", "save file", err)
+	}
+	loaded := NewSearchCache(1)
+	if restored, _, err := loaded.LoadFile(path, known); err != nil || restored != 1 {
+		t.Errorf("Test %q failed. Restored %d: %v", "load file", restored, err)
+	}
+
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(snapshotHeader{Version: snapshotVersion + 1})
+	if _, _, err := loaded.Load(&buf, known); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Test %q failed. Got: %v", "version", err)
+	}
+}
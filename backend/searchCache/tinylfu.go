@@ -0,0 +1,252 @@
+package searchCache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// region is the part of a W-TinyLFU cache an entry is in
+type region uint8
+
+const (
+	window    region = iota // new entries
+	probation               // entries admitted from the window
+	protected               // entries accessed again while on probation
+)
+
+// Shares of the budget of a W-TinyLFU cache, in percent
+const (
+	windowShare    = 1  // of the whole budget
+	protectedShare = 80 // of the main part, probation and protected
+)
+
+// tinyLFU is W-TinyLFU: new entries go to a small LRU window, and when
+// they leave it they are only admitted to the main part if they were
+// looked up more often than the entry they would evict there. Lookup
+// frequencies, including misses, are estimated by a sketch that also
+// remembers keys which are not cached. The main part is a segmented LRU
+// where entries are on probation until they are accessed again. A scan of
+// unique queries thus only passes through the window.
+type tinyLFU struct {
+	maxSize, windowMax, protectedMax int
+	size, windowSize, protectedSize  int
+
+	elems  map[CacheKey]*list.Element // elements hold *node
+	lists  [3]*list.List              // by region, most recent at the back
+	sketch sketch
+}
+
+func newTinyLFU(maxSize int) Policy {
+	windowMax := maxSize * windowShare / 100
+	p := &tinyLFU{
+		maxSize:      maxSize,
+		windowMax:    windowMax,
+		protectedMax: (maxSize - windowMax) * protectedShare / 100,
+		elems:        make(map[CacheKey]*list.Element),
+		sketch:       newSketch(minSketchWidth),
+	}
+	for i := range p.lists {
+		p.lists[i] = list.New()
+	}
+	return p
+}
+
+func (p *tinyLFU) Access(key CacheKey) {
+	p.sketch.add(key)
+	elem, ok := p.elems[key]
+	if !ok {
+		return
+	}
+	n := elem.Value.(*node)
+	switch n.region {
+	case window, protected:
+		p.lists[n.region].MoveToBack(elem)
+	case probation:
+		// Promoted, demoting the least recently used protected entries
+		p.move(elem, protected)
+		for p.protectedSize > p.protectedMax {
+			p.move(p.lists[protected].Front(), probation)
+		}
+	}
+}
+
+func (p *tinyLFU) Add(key CacheKey, size int) []CacheKey {
+	n := &node{key: key, size: size, region: window}
+	p.elems[key] = p.lists[window].PushBack(n)
+	p.size += size
+	p.windowSize += size
+	if len(p.elems) > p.sketch.width() {
+		p.sketch.grow()
+	}
+
+	// Entries leaving the window are candidates for the main part
+	var candidates []*list.Element
+	for p.windowSize > p.windowMax {
+		elem := p.lists[window].Front()
+		p.move(elem, probation)
+		candidates = append(candidates, elem)
+	}
+
+	var evicted []CacheKey
+	evict := func(elem *list.Element) {
+		key := elem.Value.(*node).key
+		p.Remove(key)
+		evicted = append(evicted, key)
+	}
+	for p.size > p.maxSize {
+		victim := p.victim(candidates)
+		switch {
+		case len(candidates) == 0:
+			evict(victim)
+		case victim == nil:
+			evict(candidates[0])
+			candidates = candidates[1:]
+		case p.sketch.estimate(candidates[0].Value.(*node).key) > p.sketch.estimate(victim.Value.(*node).key):
+			evict(victim)
+		default:
+			evict(candidates[0])
+			candidates = candidates[1:]
+		}
+	}
+	return evicted
+}
+
+// victim returns the main entry to evict next that is not one of the
+// candidates, which are at the back of probation, nil if there is none
+func (p *tinyLFU) victim(candidates []*list.Element) *list.Element {
+	for _, r := range []region{probation, protected} {
+		elem := p.lists[r].Front()
+		if elem != nil && (len(candidates) == 0 || elem != candidates[0]) {
+			return elem
+		}
+	}
+	if len(candidates) == 0 {
+		return p.lists[window].Front()
+	}
+	return nil
+}
+
+func (p *tinyLFU) Remove(key CacheKey) {
+	elem, ok := p.elems[key]
+	if !ok {
+		return
+	}
+	n := elem.Value.(*node)
+	p.lists[n.region].Remove(elem)
+	delete(p.elems, key)
+	p.size -= n.size
+	switch n.region {
+	case window:
+		p.windowSize -= n.size
+	case protected:
+		p.protectedSize -= n.size
+	}
+}
+
+func (p *tinyLFU) Keys() []CacheKey {
+	keys := make([]CacheKey, 0, len(p.elems))
+	for _, r := range []region{probation, protected, window} {
+		keys = listKeys(keys, p.lists[r])
+	}
+	return keys
+}
+
+// move moves elem to the back of region r
+func (p *tinyLFU) move(elem *list.Element, r region) {
+	n := p.lists[elem.Value.(*node).region].Remove(elem).(*node)
+	switch n.region {
+	case window:
+		p.windowSize -= n.size
+	case protected:
+		p.protectedSize -= n.size
+	}
+	n.region = r
+	switch r {
+	case window:
+		p.windowSize += n.size
+	case protected:
+		p.protectedSize += n.size
+	}
+	p.elems[n.key] = p.lists[r].PushBack(n)
+}
+
+// Sizes of a sketch
+const (
+	minSketchWidth = 64
+	sketchDepth    = 4
+	maxCount       = 15
+)
+
+// sketch is a count-min sketch estimating how often keys were added,
+// with counts that are halved periodically so that it adapts to changing
+// popularity
+type sketch struct {
+	seed      maphash.Seed
+	rows      [sketchDepth][]uint8
+	additions int
+}
+
+// newSketch returns a sketch of width counters per row, a power of two
+func newSketch(width int) sketch {
+	s := sketch{seed: maphash.MakeSeed()}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// grow doubles the width of the sketch. Counters keep their positions in
+// the wider rows, so keys keep their counts, halved as after a sample.
+func (s *sketch) grow() {
+	width := s.width()
+	for i, row := range s.rows {
+		grown := make([]uint8, 2*width)
+		for j, c := range row {
+			grown[j], grown[j+width] = c/2, c/2
+		}
+		s.rows[i] = grown
+	}
+	s.additions /= 2
+}
+
+func (s *sketch) width() int {
+	return len(s.rows[0])
+}
+
+// counters returns the position of key's counter in every row
+func (s *sketch) counters(key CacheKey) [sketchDepth]int {
+	h := maphash.Comparable(s.seed, key)
+	lo, hi := uint32(h), uint32(h>>32)
+	var pos [sketchDepth]int
+	for i := range pos {
+		pos[i] = int((lo + uint32(i)*hi) & uint32(s.width()-1))
+	}
+	return pos
+}
+
+func (s *sketch) add(key CacheKey) {
+	for i, j := range s.counters(key) {
+		if s.rows[i][j] < maxCount {
+			s.rows[i][j]++
+		}
+	}
+	// Ten additions per counter make a sample, after which counts halve
+	s.additions++
+	if s.additions >= 10*s.width() {
+		for _, row := range s.rows {
+			for j := range row {
+				row[j] /= 2
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+// estimate returns how often key was added, an overestimate at worst
+func (s *sketch) estimate(key CacheKey) uint8 {
+	count := uint8(maxCount)
+	for i, j := range s.counters(key) {
+		count = min(count, s.rows[i][j])
+	}
+	return count
+}
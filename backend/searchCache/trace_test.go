@@ -0,0 +1,141 @@
+package searchCache
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+var traceFile = flag.String("querytrace", "", "file of searches replayed by BenchmarkPolicies, one query per line, optionally after a file ID and a tab")
+
+// trace is a sequence of searches replayed against a cache
+type trace struct {
+	name     string
+	searches []CacheKey
+}
+
+// zipfTrace returns n searches for queries whose popularity follows a
+// Zipf distribution, as many users typing common queries do
+func zipfTrace(rng *rand.Rand, n, queries int) []CacheKey {
+	zipf := rand.NewZipf(rng, 1.1, 1, uint64(queries-1))
+	out := make([]CacheKey, n)
+	for i := range out {
+		out[i] = CacheKey{DocID: "doc", Query: fmt.Sprintf("q%06d", zipf.Uint64())}
+	}
+	return out
+}
+
+// scanTrace is zipfTrace with every other search coming from a user who
+// only issues queries never seen before
+func scanTrace(rng *rand.Rand, n, queries int) []CacheKey {
+	out := zipfTrace(rng, n, queries)
+	for i := 1; i < n; i += 2 {
+		out[i] = CacheKey{DocID: "doc", Query: fmt.Sprintf("s%06d", i)}
+	}
+	return out
+}
+
+// loopTrace repeats a sequence of queries slightly larger than the cache,
+// which is the worst case of LRU
+func loopTrace(n, queries int) []CacheKey {
+	out := make([]CacheKey, n)
+	for i := range out {
+		out[i] = CacheKey{DocID: "doc", Query: fmt.Sprintf("l%06d", i%queries)}
+	}
+	return out
+}
+
+// readTrace reads a trace file, see traceFile
+func readTrace(path string) ([]CacheKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []CacheKey
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		key := CacheKey{DocID: "doc", Query: line}
+		if doc, query, ok := strings.Cut(line, "\t"); ok {
+			key = CacheKey{DocID: doc, Query: query}
+		}
+		out = append(out, key)
+	}
+	return out, scanner.Err()
+}
+
+// replay runs the searches of a trace against a cache the way the search
+// handler does, storing results on a miss, and returns the hit rate
+func replay(c *SearchCache, searches []CacheKey) float64 {
+	value := results(1, 100)
+	for _, key := range searches {
+		if _, ok := c.Get(key.DocID, key.Query); !ok {
+			c.Set(key.DocID, key.Query, value)
+		}
+	}
+	return c.Stats().HitRate
+}
+
+// policyCache returns a cache for about entries of the searches of a
+// trace under the given policy
+func policyCache(tb testing.TB, policy string, entries, shards int) *SearchCache {
+	size := entrySize(CacheKey{DocID: "doc", Query: "q000000"}, results(1, 100), nil)
+	c := newSearchCache(entries*size, shards)
+	if err := c.SetPolicy(policy); err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// TestPolicyHitRates checks that the frequency based policies keep popular
+// queries cached while a user scans unique queries
+func TestPolicyHitRates(t *testing.T) {
+	searches := scanTrace(rand.New(rand.NewSource(1)), 100000, 10000)
+	rates := make(map[string]float64)
+	for _, name := range Policies() {
+		rates[name] = replay(policyCache(t, name, 500, 1), searches)
+	}
+	if rates[PolicyLFU] <= rates[PolicyLRU] || rates[PolicyTinyLFU] <= rates[PolicyLRU] {
+		t.Errorf("Test %q failed. Got hit rates: %v", "scan", rates)
+	}
+}
+
+// BenchmarkPolicies replays traces against every policy and reports the
+// hit rates. Replay a trace of real searches with -querytrace.
+func BenchmarkPolicies(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	traces := []trace{
+		{"zipf", zipfTrace(rng, 100000, 10000)},
+		{"scan", scanTrace(rng, 100000, 10000)},
+		{"loop", loopTrace(100000, 1100)},
+	}
+	if *traceFile != "" {
+		searches, err := readTrace(*traceFile)
+		if err != nil {
+			b.Fatal(err)
+		}
+		traces = append(traces, trace{"file", searches})
+	}
+
+	for _, tr := range traces {
+		for _, name := range Policies() {
+			b.Run(tr.name+"/"+name, func(b *testing.B) {
+				var rate float64
+				for i := 0; i < b.N; i++ {
+					rate = replay(policyCache(b, name, 1000, DefaultShards), tr.searches)
+				}
+				b.ReportMetric(100*rate, "hit%")
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(tr.searches)), "ns/search")
+			})
+		}
+	}
+}
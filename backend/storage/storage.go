@@ -1,29 +1,113 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"sync"
+
+	"github.com/swanckel93/fuzzy_api/analysis"
+	"github.com/swanckel93/fuzzy_api/models"
 )
 
+// Metadata describes an uploaded document
+type Metadata struct {
+	Name      string `json:"name"`
+	Format    string `json:"format"`             // detected file format, see extract.Format*
+	Encoding  string `json:"encoding,omitempty"` // character encoding of text formats, see charset
+	Sentences int    `json:"sentences"`
+	Analyzer  string `json:"analyzer,omitempty"` // applied at index and query time, "" for plain fuzzy matching
+	Language  string `json:"language,omitempty"` // dominant language, ISO 639-1
+	Chunking  string `json:"chunking"`           // unit the document is split into, see utils.Chunk*
+
+	WindowSize    int `json:"window_size,omitempty"`
+	WindowOverlap int `json:"window_overlap,omitempty"`
+
+	Records int      `json:"records,omitempty"` // rows or objects of structured formats
+	Fields  []string `json:"fields,omitempty"`  // indexed record fields
+
+	Preprocessing []string `json:"preprocessing,omitempty"` // preprocessing stages run in order, see preprocess
+
+	Hash string `json:"hash"` // SHA-256 of the searchable content, set by AddDocument
+}
+
+// Document is an uploaded file split into sentences, or into the units
+// chosen by Meta.Chunking
+type Document struct {
+	Meta      Metadata
+	Sentences []string
+	Sources   []models.Source    // location of each sentence in the original file
+	Tokens    [][]analysis.Token // analyzed terms per sentence, nil without analyzer
+	Languages []string           // language per sentence, nil unless detected per sentence
+	Records   []map[string]any   // original records of structured formats, see Source.Record
+}
+
 type DocumentStore struct {
 	mu    sync.RWMutex
-	Files map[string][]string
+	Files map[string]*Document
 }
 
 var store = &DocumentStore{
-	Files: make(map[string][]string),
+	Files: make(map[string]*Document),
 }
 
-func AddFile(filename string, sentences []string) {
+// changeListeners are called with the name of every added or replaced document
+var changeListeners struct {
+	mu    sync.RWMutex
+	funcs []func(filename string)
+}
+
+// OnChange registers f to be called after a document is added under a
+// name, replacing the document stored under it before, if any
+func OnChange(f func(filename string)) {
+	changeListeners.mu.Lock()
+	defer changeListeners.mu.Unlock()
+	changeListeners.funcs = append(changeListeners.funcs, f)
+}
+
+func AddDocument(filename string, doc *Document) {
+	doc.Meta.Name = filename
+	doc.Meta.Sentences = len(doc.Sentences)
+	doc.Meta.Hash = contentHash(doc)
 	store.mu.Lock()
-	defer store.mu.Unlock()
-	store.Files[filename] = sentences
+	store.Files[filename] = doc
+	store.mu.Unlock()
+
+	changeListeners.mu.RLock()
+	defer changeListeners.mu.RUnlock()
+	for _, f := range changeListeners.funcs {
+		f(filename)
+	}
+}
+
+// contentHash hashes everything search results are computed from, so that
+// equal hashes mean equal results
+func contentHash(doc *Document) string {
+	h := sha256.New()
+	json.NewEncoder(h).Encode(struct {
+		Analyzer  string
+		Sentences []string
+		Sources   []models.Source
+		Languages []string
+		Records   []map[string]any
+	}{doc.Meta.Analyzer, doc.Sentences, doc.Sources, doc.Languages, doc.Records})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DocumentHash returns the content hash of a stored document
+func DocumentHash(filename string) (string, bool) {
+	doc, ok := GetDocument(filename)
+	if !ok {
+		return "", false
+	}
+	return doc.Meta.Hash, true
 }
 
-func GetFile(filename string) ([]string, bool) {
+func GetDocument(filename string) (*Document, bool) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
-	sentences, ok := store.Files[filename]
-	return sentences, ok
+	doc, ok := store.Files[filename]
+	return doc, ok
 }
 
 func ListFiles() []string {
@@ -0,0 +1,233 @@
+package synonyms
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/swanckel93/fuzzy_api/search"
+)
+
+// DefaultCollection is used when a search does not name a collection
+const DefaultCollection = "default"
+
+// maxVariants caps how many query variants a single query can expand into
+const maxVariants = 16
+
+// Set maps a normalized term or phrase to the phrases it expands into
+type Set struct {
+	rules     map[string][]string
+	maxTokens int // longest left-hand side, in tokens
+}
+
+// Parse reads a Solr-style synonyms file:
+//
+//	# comment
+//	po, purchase order          (equivalent terms, each expands to all)
+//	inv, invc => invoice        (explicit mapping, left side is replaced)
+func Parse(r io.Reader) (*Set, error) {
+	s := &Set{rules: make(map[string][]string)}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if lhs, rhs, ok := strings.Cut(line, "=>"); ok {
+			from, to := splitTerms(lhs), splitTerms(rhs)
+			if len(from) == 0 || len(to) == 0 || strings.Contains(rhs, "=>") {
+				return nil, fmt.Errorf("line %d: invalid mapping %q", lineNo, line)
+			}
+			for _, f := range from {
+				s.add(f, to...)
+			}
+			continue
+		}
+
+		terms := splitTerms(line)
+		if len(terms) < 2 {
+			return nil, fmt.Errorf("line %d: need at least two synonyms", lineNo)
+		}
+		for _, t := range terms {
+			s.add(t, terms...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// add appends targets to the rule for term, skipping duplicates
+func (s *Set) add(term string, targets ...string) {
+	existing := s.rules[term]
+	for _, t := range targets {
+		dup := false
+		for _, e := range existing {
+			if e == t {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			existing = append(existing, t)
+		}
+	}
+	s.rules[term] = existing
+	s.maxTokens = max(s.maxTokens, len(strings.Fields(term)))
+}
+
+// Expand returns the search variants produced by applying the rules to every
+// matching term, longest phrase first. The original query is always the first
+// variant unless an explicit mapping replaced one of its terms.
+func (s *Set) Expand(query string) []search.Variant {
+	tokens := strings.Fields(strings.ToLower(query))
+	variants := []search.Variant{{Query: ""}}
+	original := true
+
+	for i := 0; i < len(tokens); {
+		term, targets, n := s.longestMatch(tokens[i:])
+		if n == 0 {
+			for j := range variants {
+				variants[j].Query = joinTerm(variants[j].Query, tokens[i])
+			}
+			i++
+			continue
+		}
+
+		keepsOriginal := false
+		for _, t := range targets {
+			if t == term {
+				keepsOriginal = true
+			}
+		}
+		original = original && keepsOriginal
+
+		// The untouched query is extended first, so the cap never drops it
+		var next []search.Variant
+		if keepsOriginal {
+			for _, v := range variants {
+				if v.Expansion == "" {
+					next = append(next, search.Variant{Query: joinTerm(v.Query, term)})
+				}
+			}
+		}
+		for _, v := range variants {
+			for _, t := range targets {
+				if len(next) == maxVariants {
+					break
+				}
+				if t == term && v.Expansion == "" {
+					continue // extended above
+				}
+				exp := v.Expansion
+				if t != term {
+					exp = joinExpansion(exp, term+" => "+t)
+				}
+				next = append(next, search.Variant{Query: joinTerm(v.Query, t), Expansion: exp})
+			}
+		}
+		variants = next
+		i += n
+	}
+
+	if len(tokens) == 0 {
+		return []search.Variant{{Query: query}}
+	}
+	if original {
+		// Move the untouched query to the front, exactly as the user typed it
+		for i, v := range variants {
+			if v.Expansion == "" {
+				copy(variants[1:i+1], variants[:i])
+				variants[0] = search.Variant{Query: query}
+				break
+			}
+		}
+	}
+	return variants
+}
+
+// longestMatch finds the longest rule whose phrase starts at tokens[0]
+func (s *Set) longestMatch(tokens []string) (string, []string, int) {
+	for n := min(s.maxTokens, len(tokens)); n > 0; n-- {
+		phrase := strings.Join(tokens[:n], " ")
+		if targets, ok := s.rules[phrase]; ok {
+			return phrase, targets, n
+		}
+	}
+	return "", nil, 0
+}
+
+// splitTerms splits a comma separated list into normalized terms
+func splitTerms(list string) []string {
+	var terms []string
+	for _, t := range strings.Split(list, ",") {
+		t = strings.Join(strings.Fields(strings.ToLower(t)), " ")
+		if t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+func joinTerm(query, term string) string {
+	if query == "" {
+		return term
+	}
+	return query + " " + term
+}
+
+func joinExpansion(expansion, rule string) string {
+	if expansion == "" {
+		return rule
+	}
+	return expansion + "; " + rule
+}
+
+// collectionStore holds the synonym set of each collection
+type collectionStore struct {
+	mu   sync.RWMutex
+	sets map[string]*Set
+}
+
+var collections = &collectionStore{
+	sets: make(map[string]*Set),
+}
+
+// SetCollection stores (or replaces) the synonym set of a collection
+func SetCollection(collection string, set *Set) {
+	collections.mu.Lock()
+	defer collections.mu.Unlock()
+	collections.sets[collection] = set
+}
+
+// ListCollections returns the names of all collections with synonyms
+func ListCollections() []string {
+	collections.mu.RLock()
+	defer collections.mu.RUnlock()
+	names := make([]string, 0, len(collections.sets))
+	for name := range collections.sets {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ExpandQuery expands query with the synonyms of collection. Without a
+// synonym set the query is returned as its only variant.
+func ExpandQuery(collection, query string) []search.Variant {
+	if collection == "" {
+		collection = DefaultCollection
+	}
+	collections.mu.RLock()
+	set, ok := collections.sets[collection]
+	collections.mu.RUnlock()
+	if !ok {
+		return []search.Variant{{Query: query}}
+	}
+	return set.Expand(query)
+}
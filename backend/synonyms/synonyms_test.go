@@ -0,0 +1,72 @@
+package synonyms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	set, err := Parse(strings.NewReader(`
+# purchasing terms
+po, purchase order
+inv, invc => invoice
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"No rule applies", "Delivery Note", []string{"Delivery Note"}},
+		{"Equivalent terms keep the original first", "PO 42", []string{"PO 42", "purchase order 42"}},
+		{"Multi-word phrase expands", "the purchase order", []string{"the purchase order", "the po"}},
+		{"Explicit mapping replaces the term", "inv total", []string{"invoice total"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := set.Expand(tt.query)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d variants, got %+v", len(tt.expected), got)
+			}
+			for i, v := range got {
+				if v.Query != tt.expected[i] {
+					t.Errorf("Variant %d: expected %q, got %q", i, tt.expected[i], v.Query)
+				}
+				if i > 0 && v.Expansion == "" {
+					t.Errorf("Variant %d has no expansion annotation", i)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandKeepsOriginal(t *testing.T) {
+	set, err := Parse(strings.NewReader(`
+a1, a2, a3, a4, a5
+b1, b2, b3, b4, b5
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// 25 combinations exceed the cap, the original comes last among them
+	got := set.Expand("a5 b5")
+	if len(got) != maxVariants {
+		t.Fatalf("Expected %d variants, got %d", maxVariants, len(got))
+	}
+	if got[0].Query != "a5 b5" || got[0].Expansion != "" {
+		t.Errorf("Expected the original query first, got %+v", got[0])
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"lonely", "a => ", "a => b => c"} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/swanckel93/fuzzy_api/models"
+)
+
+// Chunking strategies, i.e. the unit a document is split into for search
+const (
+	ChunkSentence  = "sentence"
+	ChunkLine      = "line"
+	ChunkParagraph = "paragraph"
+	ChunkWindow    = "window"
+)
+
+// Default window parameters, in characters
+const (
+	DefaultWindowSize    = 200
+	DefaultWindowOverlap = 50
+)
+
+// ChunkOptions selects how a document is split into units
+type ChunkOptions struct {
+	Strategy      string // one of the Chunk* constants, sentence if empty
+	Lang          string // language for sentence rules
+	WindowSize    int    // characters per window
+	WindowOverlap int    // characters shared by consecutive windows
+}
+
+// Validate fills in defaults and checks the options
+func (o *ChunkOptions) Validate() error {
+	if o.Strategy == "" {
+		o.Strategy = ChunkSentence
+	}
+	switch o.Strategy {
+	case ChunkSentence, ChunkLine, ChunkParagraph:
+		return nil
+	case ChunkWindow:
+		if o.WindowSize == 0 {
+			o.WindowSize = DefaultWindowSize
+			if o.WindowOverlap == 0 {
+				o.WindowOverlap = DefaultWindowOverlap
+			}
+		}
+		if o.WindowSize < 1 || o.WindowOverlap < 0 || o.WindowOverlap >= o.WindowSize {
+			return fmt.Errorf("window overlap must be smaller than window size")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown chunking strategy %q", o.Strategy)
+}
+
+// ChunkText splits text into units according to opts and returns each unit
+// with its location in text
+func ChunkText(text string, opts ChunkOptions) ([]string, []models.Source, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var spans []Span
+	switch opts.Strategy {
+	case ChunkSentence:
+		spans = Segment(text, RulesFor(opts.Lang))
+	case ChunkLine:
+		spans = locate(text, splitLines(text))
+	case ChunkParagraph:
+		spans = locate(text, splitParagraphs(text))
+	case ChunkWindow:
+		spans = locate(text, splitWindows(text, opts.WindowSize, opts.WindowOverlap))
+	}
+
+	units := make([]string, len(spans))
+	sources := make([]models.Source, len(spans))
+	for i, s := range spans {
+		units[i] = text[s.Start:s.End]
+		sources[i] = models.Source{
+			Offset:    s.Start,
+			Length:    s.End - s.Start,
+			Line:      s.Line,
+			Paragraph: s.Paragraph,
+		}
+	}
+	return units, sources, nil
+}
+
+// splitLines returns every non-blank line
+func splitLines(text string) []Span {
+	var spans []Span
+	start := 0
+	for start <= len(text) {
+		end := strings.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		if s, ok := trimSpan(text, start, end); ok {
+			spans = append(spans, s)
+		}
+		start = end + 1
+	}
+	return spans
+}
+
+// splitParagraphs returns blocks of text separated by blank lines
+func splitParagraphs(text string) []Span {
+	var spans []Span
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\n' {
+			continue
+		}
+		if next, ok := lineBreakBoundary(text, i); ok && text[next] == '\n' {
+			if s, ok := trimSpan(text, start, i); ok {
+				spans = append(spans, s)
+			}
+			start = next
+			i = next
+		}
+	}
+	if s, ok := trimSpan(text, start, len(text)); ok {
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// splitWindows returns windows of size characters, each starting
+// size-overlap characters after the previous one
+func splitWindows(text string, size, overlap int) []Span {
+	// Byte offset of every character, plus the end of text
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	chars := len(offsets)
+	offsets = append(offsets, len(text))
+
+	var spans []Span
+	for start := 0; start < chars; start += size - overlap {
+		end := min(start+size, chars)
+		if s, ok := trimSpan(text, offsets[start], offsets[end]); ok {
+			spans = append(spans, s)
+		}
+		if end == chars {
+			break
+		}
+	}
+	return spans
+}
+
+// locate fills in line and paragraph numbers of spans ordered by start
+func locate(text string, spans []Span) []Span {
+	line, paragraph, pos := 1, 0, 0
+	for i := range spans {
+		between := text[pos:spans[i].Start]
+		line += strings.Count(between, "\n")
+		if i > 0 {
+			paragraph += paragraphBreaks(between)
+		}
+		// Overlapping windows may start before the previous one ended
+		if spans[i].Start > pos {
+			pos = spans[i].Start
+		}
+		spans[i].Line, spans[i].Paragraph = line, paragraph
+	}
+	return spans
+}
+
+// paragraphBreaks counts the runs of blank lines in s
+func paragraphBreaks(s string) int {
+	breaks, inBreak := 0, false
+	lines := strings.Split(s, "\n")
+	// The first and last pieces are partial lines around the newlines
+	for i := 1; i < len(lines)-1; i++ {
+		blank := strings.TrimSpace(lines[i]) == ""
+		if blank && !inBreak {
+			breaks++
+		}
+		inBreak = blank
+	}
+	return breaks
+}
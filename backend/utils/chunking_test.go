@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/swanckel93/fuzzy_api/models"
+)
+
+func TestChunkText(t *testing.T) {
+	text := "2024-01-01 INFO start\n2024-01-01 WARN slow\n\nsecond block\nstill second"
+
+	tests := []struct {
+		name     string
+		opts     ChunkOptions
+		expected []string
+		sources  []models.Source
+	}{
+		{
+			name:     "Lines",
+			opts:     ChunkOptions{Strategy: ChunkLine},
+			expected: []string{"2024-01-01 INFO start", "2024-01-01 WARN slow", "second block", "still second"},
+			sources: []models.Source{
+				{Offset: 0, Length: 21, Line: 1, Paragraph: 0},
+				{Offset: 22, Length: 20, Line: 2, Paragraph: 0},
+				{Offset: 44, Length: 12, Line: 4, Paragraph: 1},
+				{Offset: 57, Length: 12, Line: 5, Paragraph: 1},
+			},
+		},
+		{
+			name:     "Paragraphs",
+			opts:     ChunkOptions{Strategy: ChunkParagraph},
+			expected: []string{"2024-01-01 INFO start\n2024-01-01 WARN slow", "second block\nstill second"},
+			sources: []models.Source{
+				{Offset: 0, Length: 42, Line: 1, Paragraph: 0},
+				{Offset: 44, Length: 25, Line: 4, Paragraph: 1},
+			},
+		},
+		{
+			name:     "Overlapping windows",
+			opts:     ChunkOptions{Strategy: ChunkWindow, WindowSize: 30, WindowOverlap: 10},
+			expected: []string{"2024-01-01 INFO start\n2024-01-", "t\n2024-01-01 WARN slow\n\nsecond", "ow\n\nsecond block\nstill second"},
+			sources: []models.Source{
+				{Offset: 0, Length: 30, Line: 1, Paragraph: 0},
+				{Offset: 20, Length: 30, Line: 1, Paragraph: 0},
+				{Offset: 40, Length: 29, Line: 2, Paragraph: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, sources, err := ChunkText(text, tt.opts)
+			if err != nil {
+				t.Fatalf("ChunkText failed: %v", err)
+			}
+			if !reflect.DeepEqual(units, tt.expected) {
+				t.Errorf("got units %q, want %q", units, tt.expected)
+			}
+			if tt.sources != nil && !reflect.DeepEqual(sources, tt.sources) {
+				t.Errorf("got sources %+v, want %+v", sources, tt.sources)
+			}
+		})
+	}
+}
+
+func TestChunkOptionsValidate(t *testing.T) {
+	invalid := []ChunkOptions{
+		{Strategy: "pages"},
+		{Strategy: ChunkWindow, WindowSize: 10, WindowOverlap: 10},
+		{Strategy: ChunkWindow, WindowSize: -1},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
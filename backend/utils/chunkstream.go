@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/swanckel93/fuzzy_api/models"
+)
+
+// Batch sizes of ChunkStream. Batches end at the first blank line after
+// streamBatchSize bytes, or are cut at a line break or space once they
+// reach streamMaxBatch bytes.
+const (
+	streamBatchSize = 256 << 10
+	streamMaxBatch  = 4 << 20
+	streamReadSize  = 64 << 10
+)
+
+// ChunkStream splits UTF-8 text read from r like ChunkText and calls emit
+// for every unit, with its location in the whole text. Text is chunked in
+// batches ending at blank lines, so the input is never held in memory as a
+// whole; windows do not span batches. If prepare is not nil, it rewrites
+// every batch before it is split, and is told which batch is the last one.
+// Locations then refer to the rewritten batches joined together.
+func ChunkStream(r io.Reader, opts ChunkOptions, prepare func(batch string, last bool) string, emit func(unit string, src models.Source)) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	var buf []byte
+	offset, line, paragraph := 0, 0, 0
+	flush := func(text string, newParagraph, final bool) error {
+		if prepare != nil {
+			text = prepare(text, final)
+		}
+		units, sources, err := ChunkText(text, opts)
+		if err != nil {
+			return err
+		}
+		last := -1
+		for i, unit := range units {
+			src := sources[i]
+			last = src.Paragraph
+			src.Offset += offset
+			src.Line += line
+			src.Paragraph += paragraph
+			emit(unit, src)
+		}
+		offset += len(text)
+		line += strings.Count(text, "\n")
+		if last >= 0 {
+			paragraph += last
+			if newParagraph {
+				paragraph++
+			}
+		}
+		return nil
+	}
+
+	chunk := make([]byte, streamReadSize)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if errors.Is(err, io.EOF) {
+			return flush(string(buf), false, true)
+		} else if err != nil {
+			return err
+		}
+		if len(buf) < streamBatchSize {
+			continue
+		}
+
+		cut, newParagraph := batchEnd(buf), true
+		if cut < 0 {
+			if len(buf) < streamMaxBatch {
+				continue
+			}
+			cut, newParagraph = forcedBatchEnd(buf), false
+		}
+		if err := flush(string(buf[:cut]), newParagraph, false); err != nil {
+			return err
+		}
+		buf = append(buf[:0], buf[cut:]...)
+	}
+}
+
+// batchEnd returns the start of the last line that follows a blank line and
+// has text on it, so that the batch before it ends with a complete
+// paragraph, or -1 if there is none
+func batchEnd(buf []byte) int {
+	for i := len(buf) - 1; i > 0; i-- {
+		if buf[i-1] != '\n' || isSpaceByte(buf[i]) {
+			continue
+		}
+		prev := i - 2
+		for prev >= 0 && buf[prev] != '\n' {
+			prev--
+		}
+		if prev >= 0 && len(bytes.TrimSpace(buf[prev+1:i-1])) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// forcedBatchEnd cuts a batch without a blank line after its last line
+// break, or its last space, or at a character boundary
+func forcedBatchEnd(buf []byte) int {
+	if i := bytes.LastIndexByte(buf, '\n'); i > 0 {
+		return i + 1
+	}
+	if i := bytes.LastIndexByte(buf, ' '); i > 0 {
+		return i + 1
+	}
+	i := len(buf)
+	for i > 0 && !utf8.RuneStart(buf[i-1]) {
+		i--
+	}
+	if i > 0 {
+		i-- // start of the last, possibly incomplete, rune
+	}
+	return max(i, 1)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
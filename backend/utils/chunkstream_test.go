@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/swanckel93/fuzzy_api/models"
+)
+
+func TestChunkStreamMatchesChunkText(t *testing.T) {
+	// Several batches worth of paragraphs, separated by one or more blank lines
+	var sb strings.Builder
+	for i := 0; sb.Len() < 3*streamBatchSize; i++ {
+		fmt.Fprintf(&sb, "Paragraph %d starts here. It has a second sentence, Dr. Müller says.\nAnd a second line.\n", i)
+		sb.WriteString(strings.Repeat("\n", 1+i%3))
+	}
+	text := sb.String()
+
+	for _, strategy := range []string{ChunkSentence, ChunkLine, ChunkParagraph} {
+		t.Run(strategy, func(t *testing.T) {
+			opts := ChunkOptions{Strategy: strategy, Lang: "de"}
+			wantUnits, wantSources, err := ChunkText(text, opts)
+			if err != nil {
+				t.Fatalf("ChunkText failed: %v", err)
+			}
+
+			var units []string
+			var sources []models.Source
+			err = ChunkStream(iotest.HalfReader(strings.NewReader(text)), opts, nil, func(unit string, src models.Source) {
+				units = append(units, unit)
+				sources = append(sources, src)
+			})
+			if err != nil {
+				t.Fatalf("ChunkStream failed: %v", err)
+			}
+			if !reflect.DeepEqual(units, wantUnits) {
+				t.Fatalf("Test %q failed. Got %d units, expected %d", strategy, len(units), len(wantUnits))
+			}
+			for i := range sources {
+				if sources[i] != wantSources[i] {
+					t.Fatalf("Test %q failed. Unit %d: got %+v, expected %+v", strategy, i, sources[i], wantSources[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChunkStreamLongParagraph(t *testing.T) {
+	// A single paragraph larger than the maximum batch is cut at line breaks
+	line := strings.Repeat("word ", 20) + "end.\n"
+	text := strings.Repeat(line, streamMaxBatch/len(line)+10)
+
+	count := 0
+	err := ChunkStream(strings.NewReader(text), ChunkOptions{Strategy: ChunkLine}, nil, func(unit string, src models.Source) {
+		count++
+		if unit != strings.TrimSpace(line) || src.Line != count || src.Paragraph != 0 {
+			t.Fatalf("Unit %d: got %q at %+v", count, unit, src)
+		}
+	})
+	if err != nil {
+		t.Fatalf("ChunkStream failed: %v", err)
+	}
+	if want := strings.Count(text, "\n"); count != want {
+		t.Errorf("Expected %d lines, got %d", want, count)
+	}
+}
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span is a sentence given as a byte range [Start, End) of the source text,
+// with the 1-based line it starts on and its 0-based paragraph index
+type Span struct {
+	Start     int
+	End       int
+	Line      int
+	Paragraph int
+}
+
+// Rules are the language specific parts of sentence segmentation
+type Rules struct {
+	// Abbreviations never end a sentence (e.g. "dr", "e.g")
+	Abbreviations map[string]bool
+	// OrdinalWords after "<number>." mean the period marks an ordinal, as in "5. Mai"
+	OrdinalWords map[string]bool
+}
+
+var segmentRules = map[string]*Rules{
+	"en": {
+		Abbreviations: wordSet(`mr mrs ms dr prof sr jr st mt rev gen col capt lt sgt hon
+			jan feb mar apr jun jul aug sep sept oct nov dec mon tue wed thu fri sat sun
+			e.g i.e vs cf approx dept est fig no nos vol ch pp p al ca ft`),
+	},
+	"de": {
+		Abbreviations: wordSet(`hr fr frl dr prof nr str st vgl bzw ca evtl ggf inkl
+			z.b d.h u.a o.ä s.o s.u z.t u.u m.e i.d.r abs abb bd jh jhd tel mio mrd
+			jan feb mär apr jun jul aug sep sept okt nov dez`),
+		OrdinalWords: wordSet(`januar februar märz april mai juni juli august september
+			oktober november dezember jahrhundert jahrestag stock platz mal`),
+	},
+	"fr": {
+		Abbreviations: wordSet(`m mm mme mlle dr pr me st ste cf p.ex av apr env
+			janv févr avr juil sept oct nov déc vol chap éd n° no`),
+		OrdinalWords: wordSet(`janvier février mars avril mai juin juillet août
+			septembre octobre novembre décembre`),
+	},
+}
+
+// RulesFor returns the segmentation rules of a language, English by default
+func RulesFor(lang string) *Rules {
+	if r, ok := segmentRules[lang]; ok {
+		return r
+	}
+	return segmentRules["en"]
+}
+
+// SplitIntoSentencesLang splits text into trimmed sentences using the rules of lang
+func SplitIntoSentencesLang(text, lang string) []string {
+	spans := Segment(text, RulesFor(lang))
+	sentences := make([]string, len(spans))
+	for i, s := range spans {
+		sentences[i] = text[s.Start:s.End]
+	}
+	return sentences
+}
+
+// Segment splits text into sentence spans. Sentences end at terminal
+// punctuation (., !, ?, …) followed by whitespace, unless the period belongs
+// to an abbreviation, initial, number or ordinal, or the next word starts in
+// lower case. Blank lines and list items always start a new sentence, and
+// trailing text without terminal punctuation is kept as the last sentence.
+func Segment(text string, rules *Rules) []Span {
+	var spans []Span
+	start := 0
+	line, lineCounted := 1, 0
+	paragraph, newParagraph := 0, false
+	emit := func(end int) {
+		if s, ok := trimSpan(text, start, end); ok {
+			line += strings.Count(text[lineCounted:s.Start], "\n")
+			lineCounted = s.Start
+			if newParagraph && len(spans) > 0 {
+				paragraph++
+			}
+			newParagraph = false
+			s.Line, s.Paragraph = line, paragraph
+			spans = append(spans, s)
+		}
+		start = end
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case r == '\n':
+			if next, ok := lineBreakBoundary(text, i); ok {
+				emit(i)
+				newParagraph = newParagraph || text[next] == '\n'
+				i = next
+				continue
+			}
+
+		case isTerminator(r):
+			runEnd := i
+			for runEnd < len(text) {
+				tr, ts := utf8.DecodeRuneInString(text[runEnd:])
+				if !isTerminator(tr) {
+					break
+				}
+				runEnd += ts
+			}
+			end := skipClosers(text, runEnd)
+			if isSentenceEnd(text, i, runEnd, end, rules) {
+				emit(end)
+			}
+			i = end
+			continue
+		}
+		i += size
+	}
+	emit(len(text))
+	return spans
+}
+
+// isSentenceEnd decides whether the terminator run text[i:runEnd], followed
+// by closing quotes/brackets up to end, finishes a sentence
+func isSentenceEnd(text string, i, runEnd, end int, rules *Rules) bool {
+	if end < len(text) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsSpace(next) {
+			// "3.50", "example.com", "?!x" – not followed by whitespace
+			return false
+		}
+	}
+	nextWord := nextWordAfter(text, end)
+	nextRune, _ := utf8.DecodeRuneInString(nextWord)
+	if nextWord != "" && unicode.IsLower(nextRune) {
+		return false
+	}
+	if text[i:runEnd] != "." {
+		return true
+	}
+
+	word := wordBefore(text, i)
+	lower := strings.ToLower(word)
+	switch {
+	case word == "":
+		return true
+	case rules.Abbreviations[lower]:
+		return false
+	case isNumber(word) && startsLine(text, i-len(word)):
+		// A numbered list marker such as "1. Open"
+		return false
+	case utf8.RuneCountInString(word) == 1 && unicode.IsLetter([]rune(word)[0]):
+		// An initial, as in "J. R. R. Tolkien", unless the text ends here
+		return nextWord == ""
+	case isNumber(word) && rules.OrdinalWords != nil:
+		return !rules.OrdinalWords[strings.ToLower(firstWord(nextWord))]
+	}
+	return true
+}
+
+// lineBreakBoundary reports whether the newline at i ends a sentence and
+// returns where scanning continues. Blank lines always do; a single newline
+// does when the next line is a list item.
+func lineBreakBoundary(text string, i int) (int, bool) {
+	j := i + 1
+	for j < len(text) && (text[j] == ' ' || text[j] == '\t' || text[j] == '\r') {
+		j++
+	}
+	if j < len(text) && text[j] == '\n' {
+		return j, true
+	}
+	return i + 1, isListItem(text[j:])
+}
+
+// isListItem reports whether line starts with a bullet or "1." / "1)" marker
+func isListItem(line string) bool {
+	r, size := utf8.DecodeRuneInString(line)
+	if strings.ContainsRune("-*•–", r) {
+		return size < len(line) && line[size] == ' '
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	return digits > 0 && digits+1 < len(line) &&
+		(line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' '
+}
+
+// wordBefore returns the non-space token ending at i, without leading quotes or brackets
+func wordBefore(text string, i int) string {
+	j := i
+	for j > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:j])
+		if unicode.IsSpace(r) {
+			break
+		}
+		j -= size
+	}
+	return strings.TrimLeft(text[j:i], "\"'“‘„«([{")
+}
+
+// nextWordAfter returns the text from the first word after i, skipping
+// whitespace and opening quotes or brackets
+func nextWordAfter(text string, i int) string {
+	return strings.TrimLeft(strings.TrimLeftFunc(text[i:], unicode.IsSpace), "\"'“‘„«([{")
+}
+
+func firstWord(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+// startsLine reports whether only spaces or tabs precede i on its line
+func startsLine(text string, i int) bool {
+	line := text[:i]
+	if nl := strings.LastIndexByte(line, '\n'); nl >= 0 {
+		line = line[nl+1:]
+	}
+	return strings.Trim(line, " \t") == ""
+}
+
+// skipClosers returns the offset after any closing quotes or brackets at i,
+// including German “…“ quotes and French guillemets preceded by a
+// (non-breaking) space
+func skipClosers(text string, i int) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == ' ' || r == '\u00a0' || r == '\u202f' {
+			if next, nextSize := utf8.DecodeRuneInString(text[i+size:]); next == '»' {
+				i += size + nextSize
+				continue
+			}
+		}
+		if !strings.ContainsRune("\"'”’“‘»«)]}", r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// trimSpan shrinks [start, end) to exclude surrounding whitespace
+func trimSpan(text string, start, end int) (Span, bool) {
+	s := text[start:end]
+	trimmedLeft := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmedLeft)
+	end = start + len(strings.TrimRightFunc(trimmedLeft, unicode.IsSpace))
+	return Span{Start: start, End: end}, end > start
+}
+
+func wordSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitIntoSentencesLang(t *testing.T) {
+	tests := []struct {
+		name     string
+		lang     string
+		text     string
+		expected []string
+	}{
+		// Basics
+		{"Empty text", "en", "", []string{}},
+		{"Whitespace only", "en", "  \n\t ", []string{}},
+		{"Single sentence", "en", "Hello world.", []string{"Hello world."}},
+		{"Two sentences", "en", "Hello world. Bye now.", []string{"Hello world.", "Bye now."}},
+		{"Question and exclamation", "en", "Is it? Yes! Good.", []string{"Is it?", "Yes!", "Good."}},
+		{"Repeated terminators", "en", "Really?! I knew it.", []string{"Really?!", "I knew it."}},
+		{"Trailing text kept", "en", "First one. And the rest", []string{"First one.", "And the rest"}},
+		{"No punctuation at all", "en", "just some words", []string{"just some words"}},
+		{"Leading whitespace trimmed", "en", "   Spaced out.   Again.  ", []string{"Spaced out.", "Again."}},
+
+		// Abbreviations
+		{"Title abbreviation", "en", "Dr. Smith arrived. He sat.", []string{"Dr. Smith arrived.", "He sat."}},
+		{"Request example", "en", "Dr. Smith paid $3.50 on Jan. 5.", []string{"Dr. Smith paid $3.50 on Jan. 5."}},
+		{"Mr and Mrs", "en", "Mr. and Mrs. Jones left. They waved.", []string{"Mr. and Mrs. Jones left.", "They waved."}},
+		{"e.g. mid sentence", "en", "Use a tool, e.g. A hammer. Done.", []string{"Use a tool, e.g. A hammer.", "Done."}},
+		{"i.e. mid sentence", "en", "One option, i.e. the first. Next.", []string{"One option, i.e. the first.", "Next."}},
+		{"Dotted acronym mid sentence", "en", "The U.S. economy grew. Stocks rose.", []string{"The U.S. economy grew.", "Stocks rose."}},
+		{"Dotted acronym at end", "en", "He moved to the U.S. Then he worked.", []string{"He moved to the U.S.", "Then he worked."}},
+		{"Initials", "en", "J. R. R. Tolkien wrote books. They sold.", []string{"J. R. R. Tolkien wrote books.", "They sold."}},
+		{"Single letter at end of text", "en", "We chose plan B.", []string{"We chose plan B."}},
+		{"Abbreviation case insensitive", "en", "See FIG. 3 below. Then stop.", []string{"See FIG. 3 below.", "Then stop."}},
+
+		// Numbers
+		{"Decimal", "en", "Pi is 3.14 roughly. Yes.", []string{"Pi is 3.14 roughly.", "Yes."}},
+		{"Version number", "en", "Install v1.2.3 now. Done.", []string{"Install v1.2.3 now.", "Done."}},
+		{"Number at sentence end", "en", "The total was 42. Next item.", []string{"The total was 42.", "Next item."}},
+		{"Thousands separator", "en", "It cost 1,000.00 dollars. Ouch.", []string{"It cost 1,000.00 dollars.", "Ouch."}},
+		{"Domain name", "en", "Visit example.com today. Thanks.", []string{"Visit example.com today.", "Thanks."}},
+
+		// Ellipses
+		{"Ellipsis before lower case", "en", "Wait... what happened? Nothing.", []string{"Wait... what happened?", "Nothing."}},
+		{"Ellipsis before upper case", "en", "I wonder... Maybe not.", []string{"I wonder...", "Maybe not."}},
+		{"Unicode ellipsis", "en", "Well… Fine.", []string{"Well…", "Fine."}},
+
+		// Quotes and brackets
+		{"Quoted speech continues", "en", `"Stop!" she said. He stopped.`, []string{`"Stop!" she said.`, "He stopped."}},
+		{"Closing quote included", "en", `He said "Go home." Then he left.`, []string{`He said "Go home."`, "Then he left."}},
+		{"Curly quotes", "en", "She asked “Why?” Nobody knew.", []string{"She asked “Why?”", "Nobody knew."}},
+		{"Opening quote starts sentence", "en", `He left. "Why?" she asked.`, []string{"He left.", `"Why?" she asked.`}},
+		{"Closing bracket", "en", "It works (mostly.) Try it.", []string{"It works (mostly.)", "Try it."}},
+		{"Single quotes", "en", "'Run!' he shouted. We ran.", []string{"'Run!' he shouted.", "We ran."}},
+
+		// Lower case continuation
+		{"Lower case after period", "en", "It was approx. ten. Then more.", []string{"It was approx. ten.", "Then more."}},
+		{"Unknown abbreviation before lower case", "en", "Ask Prof. Lee or Mt. ok. Done.", []string{"Ask Prof. Lee or Mt. ok.", "Done."}},
+
+		// Newlines
+		{"Paragraph break without punctuation", "en", "Title\n\nBody text here.", []string{"Title", "Body text here."}},
+		{"Paragraph break with spaces", "en", "First\n  \t\nSecond", []string{"First", "Second"}},
+		{"Wrapped line joins", "en", "This sentence is\nwrapped. Next.", []string{"This sentence is\nwrapped.", "Next."}},
+		{"Bullet list", "en", "Items:\n- apples\n- pears", []string{"Items:", "- apples", "- pears"}},
+		{"Numbered list", "en", "Steps:\n1. Open\n2) Close", []string{"Steps:", "1. Open", "2) Close"}},
+		{"Windows line endings", "en", "One\r\n\r\nTwo.", []string{"One", "Two."}},
+
+		// Log-like text
+		{"Log lines", "en", "ERROR: disk full\n\nWARN: retrying", []string{"ERROR: disk full", "WARN: retrying"}},
+
+		// German
+		{"German abbreviation z.B.", "de", "Obst, z.B. Äpfel, ist gesund. Ja.", []string{"Obst, z.B. Äpfel, ist gesund.", "Ja."}},
+		{"German ordinal date", "de", "Am 5. Mai kam er. Sie nicht.", []string{"Am 5. Mai kam er.", "Sie nicht."}},
+		{"German ordinal century", "de", "Im 19. Jahrhundert war das so. Heute nicht.", []string{"Im 19. Jahrhundert war das so.", "Heute nicht."}},
+		{"German number at end", "de", "Es waren 5. Danach kam nichts.", []string{"Es waren 5.", "Danach kam nichts."}},
+		{"German title", "de", "Hr. Müller ist da. Fr. Meier auch.", []string{"Hr. Müller ist da.", "Fr. Meier auch."}},
+		{"German usw. at end", "de", "Äpfel, Birnen usw. Alles da.", []string{"Äpfel, Birnen usw.", "Alles da."}},
+		{"German low quotes", "de", "Er rief „Halt!“ Dann ging er.", []string{"Er rief „Halt!“", "Dann ging er."}},
+		{"German guillemets", "de", "Sie sagte »Nein.« Er nickte.", []string{"Sie sagte »Nein.«", "Er nickte."}},
+		{"French etc. at end", "fr", "Des pommes, des poires, etc. Tout est là.", []string{"Des pommes, des poires, etc.", "Tout est là."}},
+
+		// French
+		{"French title", "fr", "M. Dupont est arrivé. Il a souri.", []string{"M. Dupont est arrivé.", "Il a souri."}},
+		{"French guillemets", "fr", "Il a dit « Bonjour. » Puis il est parti.", []string{"Il a dit « Bonjour. »", "Puis il est parti."}},
+		{"French ordinal date", "fr", "Le 1. janvier est férié. Oui.", []string{"Le 1. janvier est férié.", "Oui."}},
+
+		// Fallback
+		{"Unknown language uses English", "xx", "Dr. Who left. Bye.", []string{"Dr. Who left.", "Bye."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitIntoSentencesLang(tt.text, tt.lang)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("SplitIntoSentencesLang(%q, %q)\n got: %q\nwant: %q", tt.text, tt.lang, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSegmentSpans(t *testing.T) {
+	text := "  One. Two!\n\nThree\n- four\nfive.\n\n\nSix"
+	spans := Segment(text, RulesFor("en"))
+	want := []Span{
+		{Start: 2, End: 6, Line: 1, Paragraph: 0},
+		{Start: 7, End: 11, Line: 1, Paragraph: 0},
+		{Start: 13, End: 18, Line: 3, Paragraph: 1},
+		{Start: 19, End: 31, Line: 4, Paragraph: 1},
+		{Start: 34, End: 37, Line: 8, Paragraph: 2},
+	}
+	if !reflect.DeepEqual(spans, want) {
+		t.Fatalf("Segment(%q) = %+v, want %+v", text, spans, want)
+	}
+}
@@ -1,22 +1,12 @@
 package utils
 
 import (
-	"regexp"
 	"strings"
 )
 
-var sentenceRegex = regexp.MustCompile(`(?m)([^.!?]*[.!?])`)
-
+// SplitIntoSentences splits text into trimmed sentences using English rules
 func SplitIntoSentences(text string) []string {
-	matches := sentenceRegex.FindAllString(text, -1)
-	sentences := make([]string, 0, len(matches))
-	for _, s := range matches {
-		trimmed := strings.TrimSpace(s)
-		if trimmed != "" {
-			sentences = append(sentences, trimmed)
-		}
-	}
-	return sentences
+	return SplitIntoSentencesLang(text, "en")
 }
 
 func HighlightMatch(sentence, match string) string {
@@ -6,9 +6,10 @@ import { ArrowDown, ArrowUp } from "lucide-react";
 interface Props {
   sentence: string;
   index: number;
+  heading?: string;
 }
 
-export default function SearchResultCard({ sentence, index }: Props) {
+export default function SearchResultCard({ sentence, index, heading }: Props) {
   const { currentFile, searchQuery } = useStore();
 
   const [sentences, setSentences] = useState<string[]>([sentence]);
@@ -61,6 +62,10 @@ export default function SearchResultCard({ sentence, index }: Props) {
         <ArrowUp size={18} />
       </button>
 
+      {heading && (
+        <p className="text-xs text-gray-500 mb-1">section: {heading}</p>
+      )}
+
       <div className="prose max-w-none space-y-2 mt-6">
         {sentences.map((s, i) => (
           <p key={i}>{highlightMatch(s, searchQuery)}</p>
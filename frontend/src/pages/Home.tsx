@@ -13,13 +13,14 @@ export default function Home() {
           .slice() // clone to avoid mutating store state directly
           .sort((a, b) => {
             if (a.distance !== b.distance) return a.distance - b.distance;
-            return a.index - b.index;
+            return a.sentence_index - b.sentence_index;
           })
           .map((res) => (
             <SearchResultCard
-              key={`${res.index}-${res.distance}-${res.match}`}
+              key={`${res.sentence_index}-${res.distance}-${res.match}`}
               sentence={res.sentence}
-              index={res.index}
+              index={res.sentence_index}
+              heading={res.heading}
             />
           ))}
       </div>
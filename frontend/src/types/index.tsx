@@ -1,7 +1,21 @@
 export interface SearchResult {
     sentence: string;
+    sentence_index: number;
     index: number;
     match: string;
     distance: number;
+    expansion?: string;
+    offset: number;
+    length: number;
+    line: number;
+    paragraph: number;
+    page?: number;
+    heading?: string;
+    heading_level?: number;
+    record?: number;
+    field?: string;
+    values?: Record<string, unknown>;
+    chapter?: number;
+    chapter_title?: string;
   }
   
\ No newline at end of file